@@ -0,0 +1,120 @@
+// currency/currency.go
+package currency
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// pivot is the currency used to cross two currencies that have no direct rate
+const pivot = "USD"
+
+// codes are the active ISO-4217 currency codes
+var codes = map[string]bool{
+	"AED": true, "AFN": true, "ALL": true, "AMD": true, "ANG": true, "AOA": true, "ARS": true, "AUD": true,
+	"AWG": true, "AZN": true, "BAM": true, "BBD": true, "BDT": true, "BGN": true, "BHD": true, "BIF": true,
+	"BMD": true, "BND": true, "BOB": true, "BRL": true, "BSD": true, "BTN": true, "BWP": true, "BYN": true,
+	"BZD": true, "CAD": true, "CDF": true, "CHF": true, "CLP": true, "CNY": true, "COP": true, "CRC": true,
+	"CUP": true, "CVE": true, "CZK": true, "DJF": true, "DKK": true, "DOP": true, "DZD": true, "EGP": true,
+	"ERN": true, "ETB": true, "EUR": true, "FJD": true, "FKP": true, "GBP": true, "GEL": true, "GHS": true,
+	"GIP": true, "GMD": true, "GNF": true, "GTQ": true, "GYD": true, "HKD": true, "HNL": true, "HTG": true,
+	"HUF": true, "IDR": true, "ILS": true, "INR": true, "IQD": true, "IRR": true, "ISK": true, "JMD": true,
+	"JOD": true, "JPY": true, "KES": true, "KGS": true, "KHR": true, "KMF": true, "KPW": true, "KRW": true,
+	"KWD": true, "KYD": true, "KZT": true, "LAK": true, "LBP": true, "LKR": true, "LRD": true, "LSL": true,
+	"LYD": true, "MAD": true, "MDL": true, "MGA": true, "MKD": true, "MMK": true, "MNT": true, "MOP": true,
+	"MRU": true, "MUR": true, "MVR": true, "MWK": true, "MXN": true, "MYR": true, "MZN": true, "NAD": true,
+	"NGN": true, "NIO": true, "NOK": true, "NPR": true, "NZD": true, "OMR": true, "PAB": true, "PEN": true,
+	"PGK": true, "PHP": true, "PKR": true, "PLN": true, "PYG": true, "QAR": true, "RON": true, "RSD": true,
+	"RUB": true, "RWF": true, "SAR": true, "SBD": true, "SCR": true, "SDG": true, "SEK": true, "SGD": true,
+	"SHP": true, "SLE": true, "SOS": true, "SRD": true, "SSP": true, "STN": true, "SVC": true, "SYP": true,
+	"SZL": true, "THB": true, "TJS": true, "TMT": true, "TND": true, "TOP": true, "TRY": true, "TTD": true,
+	"TWD": true, "TZS": true, "UAH": true, "UGX": true, "USD": true, "UYU": true, "UZS": true, "VES": true,
+	"VND": true, "VUV": true, "WST": true, "XAF": true, "XCD": true, "XOF": true, "XPF": true, "YER": true,
+	"ZAR": true, "ZMW": true, "ZWL": true,
+}
+
+// Normalize upper-cases a currency code and checks it is a known ISO-4217 code
+func Normalize(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !codes[code] {
+		return "", fmt.Errorf("unknown ISO-4217 currency code %q", code)
+	}
+	return code, nil
+}
+
+// Rate returns how many units of quote one unit of base buys on the given date, using
+// the latest rate effective on or before it. Inverse rates are used when only the
+// opposite pair is loaded, and pairs without any rate are crossed through USD.
+func Rate(DB *gorm.DB, base, quote string, on time.Time) (float64, error) {
+	if base == quote {
+		return 1, nil
+	}
+
+	rate, found, err := pairRate(DB, base, quote, on)
+	if err != nil || found {
+		return rate, err
+	}
+
+	if base != pivot && quote != pivot {
+		toPivot, foundBase, err := pairRate(DB, base, pivot, on)
+		if err != nil {
+			return 0, err
+		}
+		fromPivot, foundQuote, err := pairRate(DB, pivot, quote, on)
+		if err != nil {
+			return 0, err
+		}
+		if foundBase && foundQuote {
+			return toPivot * fromPivot, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no exchange rate from %s to %s on %s", base, quote, on.Format("2006-01-02"))
+}
+
+// Convert converts an amount between currencies at the rate effective on the given date
+func Convert(DB *gorm.DB, amount float64, from, to string, on time.Time) (float64, error) {
+	rate, err := Rate(DB, from, to, on)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+// pairRate looks up the most recent direct or inverse rate for a currency pair,
+// preferring the direct rate when both take effect on the same date
+func pairRate(DB *gorm.DB, base, quote string, on time.Time) (float64, bool, error) {
+	direct, foundDirect, err := latestRate(DB, base, quote, on)
+	if err != nil {
+		return 0, false, err
+	}
+	inverse, foundInverse, err := latestRate(DB, quote, base, on)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if foundDirect && (!foundInverse || !inverse.EffectiveDate.After(direct.EffectiveDate)) {
+		return direct.Rate, true, nil
+	}
+	if foundInverse {
+		return 1 / inverse.Rate, true, nil
+	}
+	return 0, false, nil
+}
+
+// latestRate loads the newest rate for base/quote effective on or before the given date
+func latestRate(DB *gorm.DB, base, quote string, on time.Time) (models.ExchangeRate, bool, error) {
+	var rate models.ExchangeRate
+	result := DB.Where("base = ? AND quote = ? AND effective_date <= ? AND rate > 0", base, quote, on).
+		Order("effective_date DESC").
+		Limit(1).
+		Find(&rate)
+	if result.Error != nil {
+		return rate, false, result.Error
+	}
+	return rate, result.RowsAffected > 0, nil
+}
@@ -0,0 +1,122 @@
+// currency/rates.go
+package currency
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Save validates rates and stores them, replacing any existing rate for the same pair and date
+func Save(DB *gorm.DB, rates []models.ExchangeRate) error {
+	for i := range rates {
+		base, err := Normalize(rates[i].Base)
+		if err != nil {
+			return fmt.Errorf("rate %d: %v", i+1, err)
+		}
+		quote, err := Normalize(rates[i].Quote)
+		if err != nil {
+			return fmt.Errorf("rate %d: %v", i+1, err)
+		}
+		if base == quote {
+			return fmt.Errorf("rate %d: base and quote must differ", i+1)
+		}
+		if rates[i].Rate <= 0 {
+			return fmt.Errorf("rate %d: rate must be positive", i+1)
+		}
+		if rates[i].EffectiveDate.IsZero() {
+			return fmt.Errorf("rate %d: effective_date is required", i+1)
+		}
+		rates[i].ID = 0
+		rates[i].Base, rates[i].Quote = base, quote
+	}
+
+	// A batch may only touch each pair and date once, so later entries win
+	unique := make([]models.ExchangeRate, 0, len(rates))
+	index := make(map[string]int)
+	for _, rate := range rates {
+		key := rate.Base + rate.Quote + rate.EffectiveDate.Format("2006-01-02")
+		if position, ok := index[key]; ok {
+			unique[position] = rate
+			continue
+		}
+		index[key] = len(unique)
+		unique = append(unique, rate)
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base"}, {Name: "quote"}, {Name: "effective_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).Create(&unique).Error
+}
+
+// ParseCSV reads rates from CSV with the columns date,base,quote,rate (date as YYYY-MM-DD).
+// A header row is skipped if present.
+func ParseCSV(reader io.Reader, source string) ([]models.ExchangeRate, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = 4
+	csvReader.TrimLeadingSpace = true
+
+	var rates []models.ExchangeRate
+	line := 0
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+		if line == 1 && strings.EqualFold(record[0], "date") {
+			continue
+		}
+
+		date, err := time.Parse("2006-01-02", record[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", line, record[0])
+		}
+		rate, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, record[3])
+		}
+		rates = append(rates, models.ExchangeRate{
+			Base:          record[1],
+			Quote:         record[2],
+			EffectiveDate: date,
+			Rate:          rate,
+			Source:        source,
+		})
+	}
+	return rates, nil
+}
+
+// LoadFile imports the rates in a local CSV file (see ParseCSV)
+func LoadFile(DB *gorm.DB, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	rates, err := ParseCSV(file, filepath.Base(path))
+	if err != nil {
+		return 0, err
+	}
+	if err := Save(DB, rates); err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
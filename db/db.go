@@ -8,6 +8,7 @@ import (
 	"gorm.io/gorm"
 	"log"
 	"os"
+	"strings"
 )
 
 var DB *gorm.DB
@@ -45,6 +46,55 @@ func MakeMigration(DB *gorm.DB) {
 	DB.AutoMigrate(&models.User{})
 	DB.AutoMigrate(&models.Item{})
 	DB.AutoMigrate(&models.Location{})
+	DB.AutoMigrate(&models.Event{})
+	DB.AutoMigrate(&models.EventRecipient{})
+	DB.AutoMigrate(&models.Alert{})
+	DB.AutoMigrate(&models.Lot{})
+	DB.AutoMigrate(&models.Asset{})
+	DB.AutoMigrate(&models.Borrower{})
+	DB.AutoMigrate(&models.Checkout{})
+	DB.AutoMigrate(&models.Category{})
+	DB.AutoMigrate(&models.Tag{})
+	DB.AutoMigrate(&models.CustomField{})
+	DB.AutoMigrate(&models.StockMovement{})
+	DB.AutoMigrate(&models.ExchangeRate{})
+	DB.AutoMigrate(&models.Supplier{})
+	DB.AutoMigrate(&models.PurchaseOrder{})
+	DB.AutoMigrate(&models.PurchaseOrderLine{})
+	DB.AutoMigrate(&models.StocktakeSession{})
+	DB.AutoMigrate(&models.StocktakeLine{})
+	DB.AutoMigrate(&models.StocktakeCounter{})
+	DB.AutoMigrate(&models.StocktakeCount{})
+	DB.AutoMigrate(&models.KitComponent{})
+	DB.AutoMigrate(&models.Reservation{})
+	DB.AutoMigrate(&models.MaintenanceTask{})
+	DB.AutoMigrate(&models.MaintenanceLog{})
+	DB.AutoMigrate(&models.UserToken{})
+	DB.AutoMigrate(&models.RecoveryCode{})
+	DB.AutoMigrate(&models.ExternalIdentity{})
+	DB.AutoMigrate(&models.OIDCLoginState{})
+	DB.AutoMigrate(&models.APIKey{})
+	DB.AutoMigrate(&models.LoginThrottle{})
+	DB.AutoMigrate(&models.Session{})
+	DB.AutoMigrate(&models.Share{})
+	DB.AutoMigrate(&models.ShareLink{})
 
 	fmt.Println("Database migrated successfully")
 }
+
+// PromoteAdmins grants the admin role to the comma-separated emails in ADMIN_EMAILS
+func PromoteAdmins(DB *gorm.DB) {
+	var emails []string
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	if len(emails) == 0 {
+		return
+	}
+
+	if result := DB.Model(&models.User{}).Where("email IN ?", emails).Update("role", models.RoleAdmin); result.Error != nil {
+		fmt.Printf("Failed to promote admins: %v\n", result.Error)
+	}
+}
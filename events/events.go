@@ -0,0 +1,249 @@
+// events/events.go
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// Entity types and actions recorded in the event log
+const (
+	EntityItem     = "item"
+	EntityLocation = "location"
+
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionDeleted = "deleted"
+)
+
+// subscriberBuffer is how many events a subscriber can fall behind before it is told to resync
+const subscriberBuffer = 64
+
+// eventLogLock is the advisory lock that serialises event inserts, so event IDs become
+// visible in the order they were assigned and can be used as a resume cursor
+const eventLogLock = 7310026
+
+// ErrResyncRequired means a client's cursor is older than the retained event log (or it fell
+// too far behind the live stream) and it has to reload a full snapshot
+var ErrResyncRequired = fmt.Errorf("event log no longer covers this cursor, a full resync is required")
+
+// Subscription receives live events visible to one user. Lagged is closed, and no more events
+// are delivered, if the subscriber falls more than subscriberBuffer events behind.
+type Subscription struct {
+	Events chan models.Event
+	Lagged chan struct{}
+	userID uint
+}
+
+var (
+	mu          sync.RWMutex
+	subscribers = make(map[*Subscription]struct{})
+)
+
+// Outbox collects the events published in a transaction. Once the transaction has committed,
+// Broadcast sends them to subscribers; events of a rolled back transaction are never sent.
+type Outbox []models.Event
+
+// Publish records a change event in the caller's transaction, so the event is kept exactly when
+// the change is. It takes the event log lock, held until commit, so a later event can't take an
+// ID until this one is visible; call it as late in the transaction as the change allows.
+func (outbox *Outbox) Publish(tx *gorm.DB, userID uint, entityType string, entityID uint, action string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding %s event payload: %w", entityType, err)
+	}
+
+	event := models.Event{
+		UserID:     userID,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		Payload:    string(data),
+	}
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", eventLogLock).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		return err
+	}
+	if err := addRecipients(tx, &event); err != nil {
+		return err
+	}
+	*outbox = append(*outbox, event)
+	return nil
+}
+
+// addRecipients records the users the changed record is shared with, directly or through an
+// enclosing location, so they see its events too. A deleted record's shares are gone, so its
+// delete event has to be published before the record is deleted.
+func addRecipients(tx *gorm.DB, event *models.Event) error {
+	if event.UserID == 0 {
+		return nil // public locations are visible to everyone anyway
+	}
+	locationID, itemID := event.EntityID, uint(0)
+	if event.EntityType == EntityItem {
+		itemID = event.EntityID
+		if err := tx.Model(&models.Item{}).Select("location_id").Where("id = ?", itemID).Scan(&locationID).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Table("(?) AS shares", models.CoveringShares(tx, event.UserID, locationID, itemID)).
+		Where("user_id IS NOT NULL").Distinct("user_id").Pluck("user_id", &event.Recipients).Error; err != nil {
+		return err
+	}
+	if len(event.Recipients) == 0 {
+		return nil
+	}
+	recipients := make([]models.EventRecipient, len(event.Recipients))
+	for i, userID := range event.Recipients {
+		recipients[i] = models.EventRecipient{EventID: event.ID, UserID: userID}
+	}
+	return tx.Create(&recipients).Error
+}
+
+// Broadcast fans the committed events out to every subscriber allowed to see them
+func (outbox Outbox) Broadcast() {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, event := range outbox {
+		for subscription := range subscribers {
+			if !Visible(event, subscription.userID) {
+				continue
+			}
+			select {
+			case subscription.Events <- event:
+			default:
+				// Dropping the event silently would leave the client with a gap it can't see
+				fmt.Printf("Subscriber for user ID %d fell behind at event %d, asking it to resync\n", subscription.userID, event.ID)
+				close(subscription.Lagged)
+				delete(subscribers, subscription)
+			}
+		}
+	}
+}
+
+// Subscribe registers a subscription for live events visible to the given user
+func Subscribe(userID uint) *Subscription {
+	subscription := &Subscription{
+		Events: make(chan models.Event, subscriberBuffer),
+		Lagged: make(chan struct{}),
+		userID: userID,
+	}
+	mu.Lock()
+	subscribers[subscription] = struct{}{}
+	mu.Unlock()
+	return subscription
+}
+
+// Unsubscribe removes a subscription previously returned by Subscribe
+func Unsubscribe(subscription *Subscription) {
+	mu.Lock()
+	delete(subscribers, subscription)
+	mu.Unlock()
+}
+
+// Visible reports whether a user may see an event: their own records, public locations and
+// records shared with them
+func Visible(event models.Event, userID uint) bool {
+	if event.UserID == userID || event.UserID == 0 {
+		return true
+	}
+	for _, recipient := range event.Recipients {
+		if recipient == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Since loads the persisted events after lastID that are visible to the user, including those
+// of records shared with them, oldest first. It returns ErrResyncRequired if events after
+// lastID have already been pruned, including when pruning emptied the log.
+func Since(DB *gorm.DB, userID uint, lastID uint) ([]models.Event, error) {
+	var log []models.Event
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		issued, err := issuedID(tx)
+		if err != nil {
+			return err
+		}
+		var oldest uint
+		if err := tx.Model(&models.Event{}).Select("COALESCE(MIN(id), 0)").Scan(&oldest).Error; err != nil {
+			return err
+		}
+		if lastID < issued && (oldest == 0 || oldest > lastID+1) {
+			return ErrResyncRequired
+		}
+		return tx.Where("id > ? AND (user_id = ? OR user_id = 0 OR id IN (?))", lastID, userID,
+			tx.Model(&models.EventRecipient{}).Select("event_id").Where("user_id = ?", userID)).Order("id").Find(&log).Error
+	})
+	return log, err
+}
+
+// Prune deletes events older than the retention period. Clients with a cursor from before
+// the cutoff get ErrResyncRequired.
+func Prune(DB *gorm.DB, retention time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-retention)
+	var pruned int64
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("event_id IN (?)", tx.Model(&models.Event{}).Select("id").Where("created_at < ?", cutoff)).
+			Delete(&models.EventRecipient{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("created_at < ?", cutoff).Delete(&models.Event{})
+		pruned = result.RowsAffected
+		return result.Error
+	})
+	return pruned, err
+}
+
+// Message is the wire format of an event sent to clients
+type Message struct {
+	ID         uint            `json:"id"`
+	EntityType string          `json:"entity_type"`
+	EntityID   uint            `json:"entity_id"`
+	Action     string          `json:"action"`
+	Data       json.RawMessage `json:"data"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+// ToMessage converts a stored event into its wire format
+func ToMessage(event models.Event) Message {
+	return Message{
+		ID:         event.ID,
+		EntityType: event.EntityType,
+		EntityID:   event.EntityID,
+		Action:     event.Action,
+		Data:       json.RawMessage(event.Payload),
+		CreatedAt:  event.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// LatestID returns the ID of the most recent event ever logged, even if it has since been
+// pruned (0 when no event has been logged)
+func LatestID(DB *gorm.DB) (uint, error) {
+	var latest uint
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		latest, err = issuedID(tx)
+		return err
+	})
+	return latest, err
+}
+
+// issuedID returns the highest event ID handed out so far, read from the ID sequence so pruned
+// events still count. It takes the event log lock, so an event still being inserted is
+// waited for rather than counted before it is visible.
+func issuedID(tx *gorm.DB) (uint, error) {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", eventLogLock).Error; err != nil {
+		return 0, err
+	}
+	var issued uint
+	result := tx.Raw("SELECT COALESCE(pg_sequence_last_value(pg_get_serial_sequence('events', 'id')::regclass), 0)").Scan(&issued)
+	return issued, result.Error
+}
@@ -0,0 +1,22 @@
+// jobs/events.go
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sidhant-sriv/inventory-api/events"
+	"gorm.io/gorm"
+)
+
+// PruneEvents deletes change events older than EVENT_RETENTION (default 30 days)
+func PruneEvents(DB *gorm.DB) error {
+	removed, err := events.Prune(DB, intervalFromEnv("EVENT_RETENTION", 30*24*time.Hour))
+	if err != nil {
+		return fmt.Errorf("failed to prune events: %w", err)
+	}
+	if removed > 0 {
+		fmt.Printf("Event pruning: removed %d events\n", removed)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// jobs/expiry.go
+package jobs
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// EvaluateExpiringLots raises an alert for every stocked lot expiring within the warning
+// window (EXPIRY_WARNING_DAYS, default 7) and resolves alerts for lots that are used up or gone.
+func EvaluateExpiringLots(DB *gorm.DB) error {
+	warningDays := 7
+	if value := os.Getenv("EXPIRY_WARNING_DAYS"); value != "" {
+		if days, err := strconv.Atoi(value); err == nil && days >= 0 {
+			warningDays = days
+		}
+	}
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, warningDays)
+
+	var lots []models.Lot
+	if result := DB.Preload("Item").
+		Where("quantity > 0 AND expires_at IS NOT NULL AND expires_at <= ?", cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM alerts WHERE alerts.lot_id = lots.id AND alerts.type = ? AND alerts.status <> ?)", models.AlertExpiring, models.AlertResolved).
+		Find(&lots); result.Error != nil {
+		return fmt.Errorf("failed to find expiring lots: %w", result.Error)
+	}
+
+	for _, lot := range lots {
+		message := fmt.Sprintf("%s lot %s (%d) expires on %s", lot.Item.Name, lot.LotNumber, lot.Quantity, lot.ExpiresAt.Format("2006-01-02"))
+		if lot.ExpiresAt.Before(now) {
+			message = fmt.Sprintf("%s lot %s (%d) expired on %s", lot.Item.Name, lot.LotNumber, lot.Quantity, lot.ExpiresAt.Format("2006-01-02"))
+		}
+
+		lotID := lot.ID
+		alert := models.Alert{
+			UserID:  lot.UserID,
+			ItemID:  lot.ItemID,
+			LotID:   &lotID,
+			Type:    models.AlertExpiring,
+			Status:  models.AlertOpen,
+			Message: message,
+		}
+		if result := DB.Create(&alert); result.Error != nil {
+			return fmt.Errorf("failed to create expiry alert for lot %d: %w", lot.ID, result.Error)
+		}
+	}
+
+	// Resolve alerts for lots that have been consumed, deleted or had their expiry pushed out
+	result := DB.Model(&models.Alert{}).
+		Where("type = ? AND status <> ?", models.AlertExpiring, models.AlertResolved).
+		Where("NOT EXISTS (SELECT 1 FROM lots WHERE lots.id = alerts.lot_id AND lots.quantity > 0 AND lots.expires_at IS NOT NULL AND lots.expires_at <= ?)", cutoff).
+		Updates(map[string]interface{}{"status": models.AlertResolved, "resolved_at": now})
+	if result.Error != nil {
+		return fmt.Errorf("failed to resolve expiry alerts: %w", result.Error)
+	}
+
+	if len(lots) > 0 || result.RowsAffected > 0 {
+		fmt.Printf("Expiry evaluator: raised %d alerts, resolved %d\n", len(lots), result.RowsAffected)
+	}
+	return nil
+}
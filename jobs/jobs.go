@@ -0,0 +1,49 @@
+// jobs/jobs.go
+package jobs
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Start launches every background job on its own ticker
+func Start(DB *gorm.DB) {
+	every("low-stock evaluator", intervalFromEnv("LOW_STOCK_INTERVAL", 5*time.Minute), DB, EvaluateLowStock)
+	every("expiry evaluator", intervalFromEnv("EXPIRY_INTERVAL", time.Hour), DB, EvaluateExpiringLots)
+	every("reservation expiry", intervalFromEnv("RESERVATION_EXPIRY_INTERVAL", 5*time.Minute), DB, ExpireReservations)
+	every("login throttle pruning", intervalFromEnv("LOGIN_THROTTLE_PRUNE_INTERVAL", time.Hour), DB, PruneLoginThrottles)
+	every("session pruning", intervalFromEnv("SESSION_PRUNE_INTERVAL", 24*time.Hour), DB, PruneSessions)
+	every("event pruning", intervalFromEnv("EVENT_PRUNE_INTERVAL", 24*time.Hour), DB, PruneEvents)
+}
+
+// every runs fn immediately and then on each tick in a background goroutine
+func every(name string, interval time.Duration, DB *gorm.DB, fn func(*gorm.DB) error) {
+	fmt.Printf("Starting %s (every %s)\n", name, interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := fn(DB); err != nil {
+				fmt.Printf("Error running %s: %v\n", name, err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// intervalFromEnv reads a duration such as "10m" from the environment, falling back to a default
+func intervalFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		fmt.Printf("Invalid %s %q, using %s\n", key, value, fallback)
+		return fallback
+	}
+	return interval
+}
@@ -0,0 +1,28 @@
+// jobs/login_throttles.go
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// loginThrottleRetention is how long failed-login counts are kept once they stop mattering
+const loginThrottleRetention = 24 * time.Hour
+
+// PruneLoginThrottles deletes old failed-login counts, including those for addresses that
+// were never registered, so the table doesn't grow without bound
+func PruneLoginThrottles(DB *gorm.DB) error {
+	now := time.Now()
+	result := DB.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-loginThrottleRetention), now).
+		Delete(&models.LoginThrottle{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to prune login throttles: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		fmt.Printf("Login throttle pruning: removed %d entries\n", result.RowsAffected)
+	}
+	return nil
+}
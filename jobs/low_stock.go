@@ -0,0 +1,50 @@
+// jobs/low_stock.go
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// EvaluateLowStock raises an alert for every item at or below its reorder point
+// and resolves alerts for items that have been restocked.
+func EvaluateLowStock(DB *gorm.DB) error {
+	// Raise alerts for low items that don't already have an unresolved one
+	var lowItems []models.Item
+	if result := DB.Where("min_quantity > 0 AND quantity <= min_quantity").
+		Where("NOT EXISTS (SELECT 1 FROM alerts WHERE alerts.item_id = items.id AND alerts.type = ? AND alerts.status <> ?)", models.AlertLowStock, models.AlertResolved).
+		Find(&lowItems); result.Error != nil {
+		return fmt.Errorf("failed to find low-stock items: %w", result.Error)
+	}
+
+	for _, item := range lowItems {
+		alert := models.Alert{
+			UserID:  item.UserID,
+			ItemID:  item.ID,
+			Type:    models.AlertLowStock,
+			Status:  models.AlertOpen,
+			Message: fmt.Sprintf("%s is low on stock (%d left, reorder point %d)", item.Name, item.Quantity, item.MinQuantity),
+		}
+		if result := DB.Create(&alert); result.Error != nil {
+			return fmt.Errorf("failed to create low-stock alert for item %d: %w", item.ID, result.Error)
+		}
+	}
+
+	// Resolve alerts whose item is back above the threshold (or no longer tracked)
+	now := time.Now()
+	result := DB.Model(&models.Alert{}).
+		Where("type = ? AND status <> ?", models.AlertLowStock, models.AlertResolved).
+		Where("NOT EXISTS (SELECT 1 FROM items WHERE items.id = alerts.item_id AND items.min_quantity > 0 AND items.quantity <= items.min_quantity)").
+		Updates(map[string]interface{}{"status": models.AlertResolved, "resolved_at": now})
+	if result.Error != nil {
+		return fmt.Errorf("failed to resolve low-stock alerts: %w", result.Error)
+	}
+
+	if len(lowItems) > 0 || result.RowsAffected > 0 {
+		fmt.Printf("Low-stock evaluator: raised %d alerts, resolved %d\n", len(lowItems), result.RowsAffected)
+	}
+	return nil
+}
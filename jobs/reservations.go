@@ -0,0 +1,44 @@
+// jobs/reservations.go
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExpireReservations closes active reservations whose expiry has passed, returning their
+// stock to the items' available quantity.
+func ExpireReservations(DB *gorm.DB) error {
+	var reservations []models.Reservation
+	if result := DB.Where("status = ? AND expires_at IS NOT NULL AND expires_at <= ?", models.ReservationActive, time.Now()).
+		Find(&reservations); result.Error != nil {
+		return fmt.Errorf("failed to find expired reservations: %w", result.Error)
+	}
+
+	expired := 0
+	for _, reservation := range reservations {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			// Skip anything fulfilled or released since it was listed
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reservation, reservation.ID).Error; err != nil {
+				return err
+			}
+			if reservation.Status != models.ReservationActive {
+				return nil
+			}
+			expired++
+			return models.CloseReservation(tx, &reservation, models.ReservationExpired)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to expire reservation %d: %w", reservation.ID, err)
+		}
+	}
+
+	if expired > 0 {
+		fmt.Printf("Reservation expiry: expired %d reservations\n", expired)
+	}
+	return nil
+}
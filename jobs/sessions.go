@@ -0,0 +1,26 @@
+// jobs/sessions.go
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// sessionRetention is how long ended sessions are kept for the user's reference
+const sessionRetention = 30 * 24 * time.Hour
+
+// PruneSessions deletes sessions that expired or were revoked long ago
+func PruneSessions(DB *gorm.DB) error {
+	cutoff := time.Now().Add(-sessionRetention)
+	result := DB.Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff).Delete(&models.Session{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to prune sessions: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		fmt.Printf("Session pruning: removed %d sessions\n", result.RowsAffected)
+	}
+	return nil
+}
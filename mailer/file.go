@@ -0,0 +1,41 @@
+// mailer/file.go
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogMailer prints email to stdout instead of sending it. With Redact set only the
+// recipient and subject are printed, keeping tokens out of the logs.
+type LogMailer struct {
+	From   string
+	Redact bool
+}
+
+// Send prints the message
+func (m *LogMailer) Send(message Message) error {
+	if m.Redact {
+		fmt.Printf("Email to %s: %s (body not logged)\n", message.To, message.Subject)
+		return nil
+	}
+	fmt.Printf("Email to %s: %s\n%s\n", message.To, message.Subject, message.Body)
+	return nil
+}
+
+// FileMailer writes each email to its own .eml file in Dir
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// Send writes the message to a new file
+func (m *FileMailer) Send(message Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	return os.WriteFile(filepath.Join(m.Dir, name), format(m.From, message), 0o600)
+}
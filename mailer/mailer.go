@@ -0,0 +1,105 @@
+// mailer/mailer.go
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email. SMTPMailer talks to a real server (or a local stand-in such as
+// MailHog); LogMailer and FileMailer are for development.
+type Mailer interface {
+	Send(message Message) error
+}
+
+var (
+	mu      sync.Mutex
+	current Mailer
+)
+
+// Get returns the configured mailer, building it from the environment on first use
+func Get() Mailer {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		current = FromEnv()
+	}
+	return current
+}
+
+// Set replaces the mailer, e.g. with a stub
+func Set(mailer Mailer) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = mailer
+}
+
+// FromEnv builds the mailer selected by MAILER (smtp, file or log; default log).
+// In release mode (GIN_MODE=release) the log mailer leaves out message bodies, since
+// they carry password-reset and verification tokens.
+func FromEnv() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "inventory@localhost"
+	}
+
+	switch strings.ToLower(os.Getenv("MAILER")) {
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			host = "localhost"
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "25"
+		}
+		return &SMTPMailer{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return &FileMailer{Dir: dir, From: from}
+	case "", "log":
+	default:
+		fmt.Printf("Unknown MAILER %q, logging email instead\n", os.Getenv("MAILER"))
+	}
+	redact := os.Getenv("GIN_MODE") == "release"
+	if redact {
+		fmt.Println("Warning: MAILER is not set to smtp or file, so email is only logged and won't be delivered")
+	}
+	return &LogMailer{From: from, Redact: redact}
+}
+
+// headerValue strips line breaks so a value can't inject extra headers
+var headerValue = strings.NewReplacer("\r", "", "\n", "")
+
+// format renders a message with the headers mail servers and clients expect
+func format(from string, message Message) []byte {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "From: %s\r\n", headerValue.Replace(from))
+	fmt.Fprintf(&builder, "To: %s\r\n", headerValue.Replace(message.To))
+	fmt.Fprintf(&builder, "Subject: %s\r\n", headerValue.Replace(message.Subject))
+	fmt.Fprintf(&builder, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(builder.String())
+}
@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/sidhant-sriv/inventory-api/currency"
 	db "github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/jobs"
 	"github.com/sidhant-sriv/inventory-api/middleware"
 	"github.com/sidhant-sriv/inventory-api/routes"
 	"log"
 	"os"
+	"strings"
 )
 
 func main() {
@@ -28,6 +31,16 @@ func main() {
 	// Initialize database
 	DB := db.GetDB()
 	db.MakeMigration(DB)
+	db.PromoteAdmins(DB)
+
+	// Load exchange rates from a local file if one is configured
+	if ratesFile := os.Getenv("EXCHANGE_RATES_FILE"); ratesFile != "" {
+		count, err := currency.LoadFile(DB, ratesFile)
+		if err != nil {
+			log.Fatalf("Failed to load exchange rates from %s: %v", ratesFile, err)
+		}
+		fmt.Printf("Loaded %d exchange rates from %s\n", count, ratesFile)
+	}
 
 	// Set Gin to release mode in production
 	if os.Getenv("GIN_MODE") == "release" {
@@ -42,6 +55,18 @@ func main() {
 	// Initialize Gin router with default middleware
 	router := gin.Default()
 
+	// Only trust X-Forwarded-For from the proxies listed in TRUSTED_PROXIES (comma-separated
+	// addresses or CIDRs), so clients can't pick the IP that login throttling counts against
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// Add CORS middleware if needed
 	// router.Use(middleware.CORSMiddleware())
 
@@ -54,6 +79,12 @@ func main() {
 
 	// Register routes
 	routes.AuthRoutes(router) // Auth routes (public)
+	routes.MFARoutes(router)
+	routes.SessionRoutes(router)
+	routes.OIDCRoutes(router)
+	routes.AdminUserRoutes(router)
+	routes.LockoutRoutes(router)
+	routes.APIKeyRoutes(router)
 
 	// Protected user routes
 	userGroup := router.Group("/users")
@@ -63,14 +94,41 @@ func main() {
 	userGroup.Use(middleware.AuthMiddleware())
 	{
 		userGroup.GET("/:user_id", routes.GetUser())
-		userGroup.GET("/", routes.GetAllUsers())
+		userGroup.GET("/", middleware.AdminMiddleware(), routes.GetAllUsers()) // Use /admin/users to search
 		userGroup.PUT("/:user_id", routes.UpdateUser())
 		userGroup.DELETE("/:user_id", routes.DeleteUser())
 	}
 
 	// Item routes
 	routes.ItemRoutes(router)
+	routes.LotRoutes(router)
+	routes.StockRoutes(router)
+	routes.ReceiptRoutes(router)
+	routes.DepreciationRoutes(router)
+	routes.AssetRoutes(router)
+	routes.BorrowerRoutes(router)
+	routes.CategoryRoutes(router)
+	routes.TagRoutes(router)
+	routes.CustomFieldRoutes(router)
 	routes.LocationRoutes(router)
+	routes.EventRoutes(router)
+	routes.SyncRoutes(router)
+	routes.AlertRoutes(router)
+	routes.ValuationRoutes(router)
+	routes.ExchangeRateRoutes(router)
+	routes.ReportRoutes(router)
+	routes.SupplierRoutes(router)
+	routes.PurchaseOrderRoutes(router)
+	routes.StocktakeRoutes(router)
+	routes.KitRoutes(router)
+	routes.ReservationRoutes(router)
+	routes.MaintenanceRoutes(router)
+	routes.ShareRoutes(router)
+	routes.ShareLinkRoutes(router)
+
+	// Start background jobs (alert evaluators, etc.)
+	jobs.Start(DB)
+
 	// Get the port from environment variables or use default
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -0,0 +1,39 @@
+// middleware/admin.go
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/models"
+)
+
+// AdminMiddleware only lets administrators through. It must run after AuthMiddleware.
+// The role is read from the database so revoking it takes effect immediately.
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			c.Abort()
+			return
+		}
+
+		var user models.User
+		DB := db.GetDB()
+		if result := DB.Select("id", "role").First(&user, userID); result.Error != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+
+		if user.Role != models.RoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Administrator access required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -5,13 +5,26 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/sidhant-sriv/inventory-api/models"
 	"net/http"
 	"os"
 	"strings"
 )
 
+// AuthMiddleware accepts either a Bearer access token or a personal API key, sent as
+// "Authorization: Bearer inv_..." or in the X-API-Key header
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// API keys are recognised by their prefix
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			authenticateAPIKey(c, apiKey)
+			return
+		}
+		if apiKey := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); strings.HasPrefix(apiKey, models.APIKeyPrefix) {
+			authenticateAPIKey(c, apiKey)
+			return
+		}
+
 		// Get the Authorization header
 		authHeader := c.GetHeader("Authorization")
 
@@ -64,6 +77,13 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		// Set user ID in context
 		userID := uint(claims["user_id"].(float64))
+
+		// Tokens from a revoked or expired session stop working straight away
+		if sessionID, ok := claims["sid"].(float64); ok {
+			if !checkSession(c, uint(sessionID), userID) {
+				return
+			}
+		}
 		c.Set("user_id", userID)
 
 		fmt.Printf("Authenticated request from user ID: %d\n", userID)
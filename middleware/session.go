@@ -0,0 +1,45 @@
+// middleware/session.go
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/models"
+)
+
+// sessionSeenInterval limits how often a session's last-seen time is written
+const sessionSeenInterval = time.Minute
+
+// checkSession makes sure an access token's session is still active, aborting the request if not
+func checkSession(c *gin.Context, sessionID uint, userID uint) bool {
+	var session models.Session
+	DB := db.GetDB()
+	if result := DB.Where("id = ? AND user_id = ?", sessionID, userID).First(&session); result.Error != nil || !session.Active() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has ended, please log in again"})
+		c.Abort()
+		return false
+	}
+
+	now := time.Now()
+	if result := DB.Model(&models.Session{}).
+		Where("id = ? AND last_seen_at < ?", session.ID, now.Add(-sessionSeenInterval)).
+		Updates(map[string]interface{}{"last_seen_at": now, "ip": c.ClientIP()}); result.Error != nil {
+		fmt.Printf("Error recording activity for session ID %d: %v\n", session.ID, result.Error)
+	}
+
+	c.Set("session_id", session.ID)
+	return true
+}
+
+// GetSessionID returns the session of the access token used for the request, or 0
+func GetSessionID(c *gin.Context) uint {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return 0
+	}
+	return sessionID.(uint)
+}
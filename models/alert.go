@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// Alert types
+const (
+	AlertLowStock = "low_stock"
+	AlertExpiring = "expiring"
+)
+
+// Alert statuses
+const (
+	AlertOpen         = "open"
+	AlertAcknowledged = "acknowledged"
+	AlertResolved     = "resolved"
+)
+
+// Alert is raised by the background evaluators when an item needs attention
+type Alert struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	UserID         uint       `gorm:"index" json:"user_id"`
+	ItemID         uint       `gorm:"index" json:"item_id"`
+	Item           Item       `gorm:"foreignKey:ItemID" json:"item,omitempty"`
+	LotID          *uint      `gorm:"index" json:"lot_id,omitempty"` // set for lot-level alerts such as expiry
+	Type           string     `gorm:"index" json:"type"`
+	Status         string     `gorm:"index" json:"status"`
+	Message        string     `json:"message"`
+	SnoozedUntil   *time.Time `json:"snoozed_until,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix starts every API key so keys are easy to recognise in configs and leak scanners
+const APIKeyPrefix = "inv_"
+
+// API key scope levels. Each level includes the ones before it.
+const (
+	ScopeRead  = "read"  // GET requests
+	ScopeWrite = "write" // POST, PUT and PATCH requests
+	ScopeAdmin = "admin" // DELETE requests
+)
+
+// APIKeyResources are the resources a key can be scoped to, e.g. "items:read". "*" stands
+// for all of them. Accounts, sessions, API keys, shares and share links are never reachable
+// with a key.
+var APIKeyResources = []string{
+	"admin", "alerts", "assets", "borrowers", "categories", "events", "exchange-rates", "fields",
+	"items", "locations", "maintenance", "purchase-orders", "reports", "reservations",
+	"stocktakes", "suppliers", "sync", "tags", "valuation",
+}
+
+// APIKey is a long-lived credential for scripts and integrations. It acts as its user,
+// limited to its scopes. Only the SHA-256 hash of the key is stored.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index" json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `gorm:"uniqueIndex" json:"prefix"` // the first part of the key, shown so keys can be told apart
+	KeyHash    string     `json:"-"`
+	Scopes     StringList `gorm:"type:jsonb" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Active reports whether the key can still be used
+func (key APIKey) Active() bool {
+	return key.RevokedAt == nil && (key.ExpiresAt == nil || time.Now().Before(*key.ExpiresAt))
+}
+
+// Allows reports whether one of the key's scopes covers the resource at the given level
+func (key APIKey) Allows(resource string, level string) bool {
+	for _, scope := range key.Scopes {
+		scopeResource, scopeLevel := splitScope(scope)
+		if (scopeResource == resource || scopeResource == "*") && scopeLevelRank(scopeLevel) >= scopeLevelRank(level) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidScope reports whether a scope is a known resource (or "*") and level
+func ValidScope(scope string) bool {
+	resource, level := splitScope(scope)
+	if scopeLevelRank(level) == 0 {
+		return false
+	}
+	if resource == "*" {
+		return true
+	}
+	for _, known := range APIKeyResources {
+		if resource == known {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyPrefixOf returns the stored prefix of a key: "inv_" and the 8 characters after it
+func APIKeyPrefixOf(key string) (string, bool) {
+	prefixLength := len(APIKeyPrefix) + 8
+	if !strings.HasPrefix(key, APIKeyPrefix) || len(key) <= prefixLength+1 || key[prefixLength] != '_' {
+		return "", false
+	}
+	return key[:prefixLength], true
+}
+
+// HashAPIKey is the SHA-256 of a key; keys are random so no salt is needed
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// splitScope separates "items:read" into its resource and level
+func splitScope(scope string) (string, string) {
+	resource, level, _ := strings.Cut(scope, ":")
+	return resource, level
+}
+
+// scopeLevelRank orders the levels; unknown levels rank 0
+func scopeLevelRank(level string) int {
+	switch level {
+	case ScopeRead:
+		return 1
+	case ScopeWrite:
+		return 2
+	case ScopeAdmin:
+		return 3
+	}
+	return 0
+}
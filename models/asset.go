@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+// Asset statuses
+const (
+	AssetAvailable  = "available"
+	AssetCheckedOut = "checked_out"
+	AssetRetired    = "retired"
+)
+
+// Asset is a single serial-numbered unit of an item (e.g. one specific laptop)
+type Asset struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	ItemID       uint       `gorm:"index" json:"item_id"`
+	Item         Item       `gorm:"foreignKey:ItemID" json:"-"`
+	UserID       uint       `gorm:"uniqueIndex:idx_asset_user_serial" json:"user_id"`
+	SerialNumber string     `gorm:"uniqueIndex:idx_asset_user_serial" json:"serial_number"`
+	Status       string     `gorm:"index" json:"status"`
+	Notes        string     `json:"notes"`
+	Checkouts    []Checkout `gorm:"foreignKey:AssetID" json:"checkouts,omitempty"` // custody history
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Borrower is a person assets can be lent to; they don't need an account
+type Borrower struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id"` // owner of the contact entry
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Checkout records one period of custody of an asset by a borrower
+type Checkout struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	AssetID      uint       `gorm:"index" json:"asset_id"`
+	Asset        Asset      `gorm:"foreignKey:AssetID" json:"asset,omitempty"`
+	BorrowerID   uint       `gorm:"index" json:"borrower_id"`
+	Borrower     Borrower   `gorm:"foreignKey:BorrowerID" json:"borrower,omitempty"`
+	UserID       uint       `gorm:"index" json:"user_id"`
+	CheckedOutAt time.Time  `json:"checked_out_at"`
+	DueAt        *time.Time `gorm:"index" json:"due_at,omitempty"`
+	ReturnedAt   *time.Time `gorm:"index" json:"returned_at,omitempty"` // nil while the asset is out
+	Notes        string     `json:"notes"`
+	ReturnNotes  string     `json:"return_notes"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
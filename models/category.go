@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Category groups items in a user-defined hierarchy (e.g. Electronics > Cables)
+type Category struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	Name      string     `json:"name"`
+	ParentID  *uint      `gorm:"index" json:"parent_id"` // nil for top-level categories
+	Children  []Category `gorm:"foreignKey:ParentID" json:"children,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Tag is a free-form label that can be attached to any number of items
+type Tag struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"uniqueIndex:idx_tag_user_name" json:"user_id"`
+	Name      string    `gorm:"uniqueIndex:idx_tag_user_name" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// Custom field types
+const (
+	FieldText    = "text"
+	FieldNumber  = "number"
+	FieldDate    = "date"
+	FieldEnum    = "enum"
+	FieldBoolean = "boolean"
+	FieldURL     = "url"
+)
+
+// CustomField defines an extra attribute for items in a category and its subcategories.
+// Keys are unique per user so a key always has the same type wherever it's used.
+type CustomField struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"uniqueIndex:idx_custom_field_user_key" json:"user_id"`
+	CategoryID uint       `gorm:"index" json:"category_id"`
+	Category   Category   `gorm:"foreignKey:CategoryID" json:"-"`
+	Key        string     `gorm:"uniqueIndex:idx_custom_field_user_key" json:"key"` // name used in Item.CustomFields
+	Label      string     `json:"label"`
+	Type       string     `json:"type"`
+	Options    StringList `gorm:"type:jsonb" json:"options,omitempty"` // allowed values for enum fields
+	Required   bool       `json:"required"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
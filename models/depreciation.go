@@ -0,0 +1,77 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Depreciation methods
+const (
+	DepreciationNone             = "none"
+	DepreciationStraightLine     = "straight_line"     // loses the same amount every month
+	DepreciationDecliningBalance = "declining_balance" // loses a fixed share of the remaining value every year
+)
+
+// MaxDecliningRate caps the yearly declining-balance rate. Double-declining on a useful life of
+// two years or less would otherwise reach 100% and write the item off in its first year.
+const MaxDecliningRate = 0.9
+
+// averageMonth is the length of a month in nanoseconds, used to turn elapsed time into months of use
+const averageMonth = 365.25 / 12 * 24 * float64(time.Hour)
+
+// ValueOn returns the depreciated per-unit value of an item on the given date.
+// Depreciation runs from the purchase date (or creation date if unknown) and never
+// goes below the salvage value.
+func (item Item) ValueOn(date time.Time) float64 {
+	if item.DepreciationMethod == "" || item.DepreciationMethod == DepreciationNone || item.UsefulLifeMonths <= 0 {
+		return item.PurchasePrice
+	}
+
+	start := item.CreatedAt
+	if item.PurchaseDate != nil {
+		start = *item.PurchaseDate
+	}
+	if !date.After(start) {
+		return item.PurchasePrice
+	}
+	months := float64(date.Sub(start)) / averageMonth
+
+	salvage := math.Min(item.SalvageValue, item.PurchasePrice)
+	var value float64
+	switch item.DepreciationMethod {
+	case DepreciationStraightLine:
+		used := math.Min(months/float64(item.UsefulLifeMonths), 1)
+		value = item.PurchasePrice - (item.PurchasePrice-salvage)*used
+	case DepreciationDecliningBalance:
+		rate := item.DepreciationRate
+		if rate <= 0 {
+			rate = 2 / (float64(item.UsefulLifeMonths) / 12)
+		}
+		rate = math.Min(rate, MaxDecliningRate)
+		value = item.PurchasePrice * math.Pow(1-rate, months/12)
+		// Stop at the salvage value, and write down to it once the useful life is over
+		if value < salvage || months >= float64(item.UsefulLifeMonths) {
+			value = salvage
+		}
+	default:
+		return item.PurchasePrice
+	}
+
+	return math.Round(math.Max(value, salvage)*100) / 100
+}
+
+// AfterFind fills in the computed current value and available quantity whenever an item is loaded
+func (item *Item) AfterFind(tx *gorm.DB) error {
+	item.CurrentValue = item.ValueOn(time.Now())
+	item.AvailableQuantity = item.Available()
+	return nil
+}
+
+// AfterSave keeps the computed fields in step with the saved item
+func (item *Item) AfterSave(tx *gorm.DB) error {
+	item.CurrentValue = item.ValueOn(time.Now())
+	item.AvailableQuantity = item.Available()
+	return nil
+}
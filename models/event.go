@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Event is a persisted change record for items and locations. The event log
+// backs the real-time stream (and its Last-Event-ID resume).
+type Event struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"index" json:"user_id"`     // owner of the changed record (0 for public locations)
+	EntityType string    `gorm:"index" json:"entity_type"` // "item" or "location"
+	EntityID   uint      `json:"entity_id"`
+	Action     string    `json:"action"`                   // "created", "updated" or "deleted"
+	Payload    string    `gorm:"type:text" json:"payload"` // JSON snapshot of the record after the change
+	CreatedAt  time.Time `json:"created_at"`
+	Recipients []uint    `gorm:"-" json:"-"` // users the record is shared with, set when the event is published
+}
+
+// EventRecipient records that an event is visible to a user the changed record is shared with
+type EventRecipient struct {
+	EventID uint `gorm:"primaryKey"`
+	UserID  uint `gorm:"primaryKey;index"`
+}
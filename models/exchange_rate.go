@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// ExchangeRate says how many units of Quote one unit of Base buys from EffectiveDate
+// until a newer rate for the pair takes effect
+type ExchangeRate struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	Base          string    `gorm:"size:3;uniqueIndex:idx_exchange_rate_pair_date" json:"base"`
+	Quote         string    `gorm:"size:3;uniqueIndex:idx_exchange_rate_pair_date" json:"quote"`
+	EffectiveDate time.Time `gorm:"type:date;uniqueIndex:idx_exchange_rate_pair_date" json:"effective_date"`
+	Rate          float64   `json:"rate"`
+	Source        string    `json:"source"` // where the rate came from, e.g. "api" or a file name
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// ExternalIdentity links an account at an OpenID Connect provider to a user
+type ExternalIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"index" json:"user_id"`
+	Provider    string     `gorm:"uniqueIndex:idx_identity_provider_subject" json:"provider"`
+	Subject     string     `gorm:"uniqueIndex:idx_identity_provider_subject" json:"subject"` // the provider's stable "sub" claim
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// OIDCLoginState remembers an authorization request between the redirect to the
+// provider and its callback. Each state is single-use and short-lived.
+type OIDCLoginState struct {
+	ID           uint   `gorm:"primaryKey"`
+	State        string `gorm:"uniqueIndex"`
+	Provider     string
+	CodeVerifier string // PKCE verifier whose S256 challenge was sent to the provider
+	Nonce        string
+	ExpiresAt    time.Time `gorm:"index"`
+	CreatedAt    time.Time
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// KitComponent says how many units of a component item go into one unit of a kit item
+type KitComponent struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	KitItemID       uint      `gorm:"uniqueIndex:idx_kit_component" json:"kit_item_id"`
+	ComponentItemID uint      `gorm:"uniqueIndex:idx_kit_component;index" json:"component_item_id"`
+	Component       Item      `gorm:"foreignKey:ComponentItemID" json:"component"`
+	Quantity        int       `json:"quantity"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
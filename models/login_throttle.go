@@ -0,0 +1,35 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LoginThrottle counts recent failed logins for an email address, a client IP or a
+// password-protected share link. Emails are tracked whether or not an account exists,
+// so lockouts don't reveal which addresses are registered.
+type LoginThrottle struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Key           string     `gorm:"uniqueIndex" json:"key"` // "email:<address>", "ip:<address>" or "share-link:<id>"
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `gorm:"index" json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// EmailThrottleKey is the throttle key for an account's email address
+func EmailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IPThrottleKey is the throttle key for a client address
+func IPThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// ShareLinkThrottleKey is the throttle key for guesses at a share link's password
+func ShareLinkThrottleKey(linkID uint) string {
+	return fmt.Sprintf("share-link:%d", linkID)
+}
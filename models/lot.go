@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// Lot is a batch of an item received together, with its own quantity and expiry date
+type Lot struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	ItemID    uint       `gorm:"index" json:"item_id"`
+	Item      Item       `gorm:"foreignKey:ItemID" json:"-"`
+	UserID    uint       `gorm:"index" json:"user_id"` // copied from the item for scoping queries
+	LotNumber string     `json:"lot_number"`
+	Quantity  int        `json:"quantity"`
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at"` // nil for lots that don't expire
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// MaintenanceTask is recurring upkeep for an item. It repeats either every IntervalDays
+// or on a cron-style Rule ("minute hour day month weekday"); exactly one is set.
+type MaintenanceTask struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `gorm:"index" json:"user_id"`
+	ItemID          uint       `gorm:"index" json:"item_id"`
+	Item            Item       `gorm:"foreignKey:ItemID" json:"item,omitempty"`
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	IntervalDays    int        `json:"interval_days"`
+	Rule            string     `json:"rule"`
+	LastCompletedAt *time.Time `json:"last_completed_at"`
+	NextDueAt       time.Time  `gorm:"index" json:"next_due_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// MaintenanceLog records one completion of a maintenance task
+type MaintenanceLog struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TaskID      uint      `gorm:"index" json:"task_id"`
+	ItemID      uint      `gorm:"index" json:"item_id"`
+	UserID      uint      `gorm:"index" json:"user_id"`
+	CompletedAt time.Time `json:"completed_at"`
+	Notes       string    `json:"notes"`
+	Cost        float64   `json:"cost"`
+	Currency    string    `gorm:"size:3" json:"currency"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+)
+
+// Purchase order statuses
+const (
+	PurchaseOrderDraft             = "draft"
+	PurchaseOrderOrdered           = "ordered"
+	PurchaseOrderPartiallyReceived = "partially_received"
+	PurchaseOrderReceived          = "received"
+	PurchaseOrderCancelled         = "cancelled"
+)
+
+// Supplier is a vendor items are bought from
+type Supplier struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	Website   string    `json:"website"`
+	Currency  string    `gorm:"size:3" json:"currency"` // default currency for purchase orders
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PurchaseOrder is an order placed with a supplier. Lines are received into a location,
+// possibly over several deliveries.
+type PurchaseOrder struct {
+	ID         uint                `gorm:"primaryKey" json:"id"`
+	UserID     uint                `gorm:"index" json:"user_id"`
+	SupplierID uint                `gorm:"index" json:"supplier_id"`
+	Supplier   Supplier            `gorm:"foreignKey:SupplierID" json:"supplier,omitempty"`
+	Number     string              `json:"number"`
+	Status     string              `gorm:"index" json:"status"`
+	Currency   string              `gorm:"size:3" json:"currency"`
+	Notes      string              `json:"notes"`
+	OrderedAt  *time.Time          `json:"ordered_at"`
+	ExpectedAt *time.Time          `json:"expected_at"`
+	ReceivedAt *time.Time          `json:"received_at"` // when the last line was fully received
+	Lines      []PurchaseOrderLine `gorm:"foreignKey:PurchaseOrderID" json:"lines,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+// PurchaseOrderLine is a quantity of one item on a purchase order
+type PurchaseOrderLine struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	PurchaseOrderID  uint      `gorm:"index" json:"purchase_order_id"`
+	ItemID           uint      `gorm:"index" json:"item_id"`
+	Item             Item      `gorm:"foreignKey:ItemID" json:"-"`
+	Quantity         int       `json:"quantity"`
+	ReceivedQuantity int       `json:"received_quantity"`
+	UnitCost         float64   `json:"unit_cost"` // in the order's currency
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
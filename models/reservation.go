@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Reservation statuses
+const (
+	ReservationActive    = "active"
+	ReservationFulfilled = "fulfilled"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+// Reservation earmarks some of an item's stock for a holder (a person or project)
+// so it isn't counted as available. Active reservations are summed into Item.ReservedQuantity.
+type Reservation struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	ItemID    uint       `gorm:"index" json:"item_id"`
+	Item      Item       `gorm:"foreignKey:ItemID" json:"item,omitempty"`
+	Quantity  int        `json:"quantity"`
+	Holder    string     `json:"holder"`
+	Note      string     `json:"note"`
+	Status    string     `gorm:"index" json:"status"`
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at"` // nil for holds that last until released
+	ClosedAt  *time.Time `json:"closed_at"`               // when it was fulfilled, released or expired
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// CloseReservation ends an active reservation with the given status and returns its
+// quantity to the item's available stock. The reservation should be locked by the caller.
+func CloseReservation(tx *gorm.DB, reservation *Reservation, status string) error {
+	now := time.Now()
+	if err := tx.Model(reservation).Updates(map[string]interface{}{"status": status, "closed_at": now}).Error; err != nil {
+		return err
+	}
+	reservation.Status = status
+	reservation.ClosedAt = &now
+	return tx.Model(&Item{}).Where("id = ?", reservation.ItemID).
+		Update("reserved_quantity", gorm.Expr("GREATEST(reserved_quantity - ?, 0)", reservation.Quantity)).Error
+}
+
+// Available is the quantity on hand that isn't reserved
+func (item Item) Available() int {
+	if item.ReservedQuantity >= item.Quantity {
+		return 0
+	}
+	return item.Quantity - item.ReservedQuantity
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session is one signed-in device. Each refresh rotates RefreshTokenID, so the session is
+// also the refresh token family: presenting an older refresh token means it was copied,
+// and the whole session is revoked. Access tokens carry the session ID and stop working
+// as soon as the session is revoked.
+type Session struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	UserID         uint       `gorm:"index" json:"user_id"`
+	DeviceName     string     `json:"device_name"`
+	IP             string     `json:"ip"`
+	UserAgent      string     `json:"user_agent"`
+	RefreshTokenID string     `json:"-"` // jti of the newest refresh token
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	ExpiresAt      time.Time  `gorm:"index" json:"expires_at"` // when the newest refresh token expires
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Active reports whether the session can still be used
+func (session Session) Active() bool {
+	return session.RevokedAt == nil && time.Now().Before(session.ExpiresAt)
+}
+
+// RevokeUserSessions signs a user out everywhere
+func RevokeUserSessions(tx *gorm.DB, userID uint) error {
+	return tx.Model(&Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", time.Now()).Error
+}
+
+// RevokeOtherUserSessions signs a user out everywhere except the given session
+// (0 keeps none)
+func RevokeOtherUserSessions(tx *gorm.DB, userID uint, keepSessionID uint) error {
+	return tx.Model(&Session{}).Where("user_id = ? AND id != ? AND revoked_at IS NULL", userID, keepSessionID).Update("revoked_at", time.Now()).Error
+}
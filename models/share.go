@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Share permissions. Editors can also do everything viewers can.
+const (
+	ShareViewer = "viewer"
+	ShareEditor = "editor"
+)
+
+// Share gives another user access to one of the owner's locations or items. A location share
+// covers the items in it and in every location nested inside it. Exactly one of LocationID
+// and ItemID is set. Shares sent to an address without a verified account wait with no
+// UserID until the address is verified.
+type Share struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	OwnerID    uint      `gorm:"index" json:"owner_id"`
+	Owner      User      `gorm:"foreignKey:OwnerID" json:"-"`
+	UserID     *uint     `gorm:"uniqueIndex:idx_share_location;uniqueIndex:idx_share_item" json:"-"` // who the share is for
+	User       *User     `gorm:"foreignKey:UserID" json:"-"`
+	Email      string    `gorm:"index" json:"email"` // address the share was sent to
+	LocationID *uint     `gorm:"uniqueIndex:idx_share_location" json:"location_id"`
+	Location   *Location `gorm:"foreignKey:LocationID" json:"-"`
+	ItemID     *uint     `gorm:"uniqueIndex:idx_share_item" json:"item_id"`
+	Item       *Item     `gorm:"foreignKey:ItemID" json:"-"`
+	Permission string    `json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SharePermissionRank orders permissions so the strongest of several shares wins; unknown ranks 0
+func SharePermissionRank(permission string) int {
+	switch permission {
+	case ShareViewer:
+		return 1
+	case ShareEditor:
+		return 2
+	}
+	return 0
+}
+
+// CoveringShares selects the shares the owner has given on the item or on the location or any
+// location enclosing it. Pass 0 for the item when the record is a location.
+func CoveringShares(DB *gorm.DB, ownerID uint, locationID uint, itemID uint) *gorm.DB {
+	return DB.Raw(`WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM locations WHERE id = ?
+			UNION
+			SELECT locations.id, locations.parent_id FROM locations JOIN ancestors ON locations.id = ancestors.parent_id
+		)
+		SELECT * FROM shares
+		WHERE owner_id = ? AND (item_id = ? OR location_id IN (SELECT id FROM ancestors))`,
+		locationID, ownerID, itemID)
+}
+
+// SharedLocations selects (id, owner_id) for every location shared with the user, directly or
+// through a location enclosing it. A share only covers what belongs to the user who gave it,
+// so callers match the owner as well as the ID.
+func SharedLocations(DB *gorm.DB, userID uint) *gorm.DB {
+	return DB.Raw(`WITH RECURSIVE shared AS (
+			SELECT location_id AS id, owner_id FROM shares WHERE user_id = ? AND location_id IS NOT NULL
+			UNION
+			SELECT locations.id, shared.owner_id FROM locations JOIN shared ON locations.parent_id = shared.id
+		)
+		SELECT id, owner_id FROM shared`, userID)
+}
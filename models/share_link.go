@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ShareLink is a public, read-only link to one of the owner's locations or items for people
+// without an account. Only the SHA-256 hash of the link's token is stored. Exactly one of
+// LocationID and ItemID is set.
+type ShareLink struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	OwnerID      uint       `gorm:"index" json:"owner_id"`
+	Label        string     `json:"label"`
+	LocationID   *uint      `gorm:"index" json:"location_id"`
+	Location     *Location  `gorm:"foreignKey:LocationID" json:"-"`
+	ItemID       *uint      `gorm:"index" json:"item_id"`
+	Item         *Item      `gorm:"foreignKey:ItemID" json:"-"`
+	TokenHash    string     `gorm:"uniqueIndex" json:"-"`
+	TokenPrefix  string     `json:"token_prefix"` // start of the token, so links can be told apart
+	PasswordHash string     `json:"-"`            // empty when no password is needed
+	HasPassword  bool       `gorm:"-" json:"has_password"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ViewCount    int64      `json:"view_count"`
+	LastViewedAt *time.Time `json:"last_viewed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Active reports whether the link can still be opened
+func (link ShareLink) Active() bool {
+	return link.RevokedAt == nil && (link.ExpiresAt == nil || time.Now().Before(*link.ExpiresAt))
+}
+
+// AfterFind fills in HasPassword
+func (link *ShareLink) AfterFind(tx *gorm.DB) error {
+	link.HasPassword = link.PasswordHash != ""
+	return nil
+}
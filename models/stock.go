@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// Stock movement reasons
+const (
+	MovementOpening     = "opening"     // initial quantity when an item is created
+	MovementAdjustment  = "adjustment"  // manual quantity edits
+	MovementLot         = "lot"         // lot created, edited or removed
+	MovementConsume     = "consume"     // stock used up
+	MovementPurchase    = "purchase"    // stock received against a purchase order
+	MovementStocktake   = "stocktake"   // correction after a physical count
+	MovementAssembly    = "assembly"    // components turned into kits, or kits made from them
+	MovementDisassembly = "disassembly" // kits broken back down into their components
+	MovementReservation = "reservation" // reserved stock handed over to its holder
+)
+
+// StockMovement is an entry in an item's stock ledger. Positive quantities are stock
+// coming in (with the unit cost paid), negative quantities are stock going out.
+type StockMovement struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"index" json:"user_id"`
+	ItemID     uint      `gorm:"index" json:"item_id"`
+	LocationID uint      `json:"location_id"`
+	Quantity   int       `json:"quantity"`
+	UnitCost   float64   `json:"unit_cost"` // cost per unit for incoming stock
+	Currency   string    `gorm:"size:3" json:"currency"`
+	Reason     string    `gorm:"index" json:"reason"`
+	Reference  string    `json:"reference"` // free-form pointer to what caused the movement
+	OccurredAt time.Time `gorm:"index" json:"occurred_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+)
+
+// Stocktake session statuses
+const (
+	StocktakeOpen      = "open"
+	StocktakeApproved  = "approved"
+	StocktakeCancelled = "cancelled"
+)
+
+// StocktakeSession is a physical count of a location and everything nested inside it.
+// Expected quantities are snapshotted when the session opens.
+type StocktakeSession struct {
+	ID         uint               `gorm:"primaryKey" json:"id"`
+	UserID     uint               `gorm:"index" json:"user_id"` // owner; only they can approve
+	LocationID uint               `json:"location_id"`
+	Name       string             `json:"name"`
+	Notes      string             `json:"notes"`
+	Status     string             `gorm:"index" json:"status"`
+	ApprovedAt *time.Time         `json:"approved_at"`
+	Lines      []StocktakeLine    `gorm:"foreignKey:SessionID" json:"lines,omitempty"`
+	Counters   []StocktakeCounter `gorm:"foreignKey:SessionID" json:"counters,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// StocktakeLine is the expected and counted quantity of one item in a session
+type StocktakeLine struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	SessionID        uint       `gorm:"uniqueIndex:idx_stocktake_line_item" json:"session_id"`
+	ItemID           uint       `gorm:"uniqueIndex:idx_stocktake_line_item" json:"item_id"`
+	Item             Item       `gorm:"foreignKey:ItemID" json:"-"`
+	ItemName         string     `json:"item_name"`
+	LocationID       uint       `json:"location_id"`
+	ExpectedQuantity int        `json:"expected_quantity"`
+	CountedQuantity  *int       `json:"counted_quantity"` // nil until someone counts the item
+	CountedAt        *time.Time `json:"counted_at"`
+}
+
+// StocktakeCounter is another user invited by email to submit counts in a session. UserID stays
+// empty until an account has verified the address, and is never shown, so invitations don't
+// reveal who is registered.
+type StocktakeCounter struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SessionID uint      `gorm:"uniqueIndex:idx_stocktake_counter" json:"session_id"`
+	UserID    *uint     `gorm:"uniqueIndex:idx_stocktake_counter" json:"-"`
+	Email     string    `gorm:"index" json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StocktakeCount is a single submitted count, kept as an audit trail
+type StocktakeCount struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SessionID uint      `gorm:"index" json:"session_id"`
+	LineID    uint      `json:"line_id"`
+	UserID    uint      `json:"user_id"`
+	Quantity  int       `json:"quantity"`
+	Mode      string    `json:"mode"` // "add" (e.g. one barcode scan) or "set"
+	CreatedAt time.Time `json:"created_at"`
+}
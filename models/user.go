@@ -5,37 +5,80 @@ import (
 	"time"
 )
 
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin" // can manage shared reference data such as exchange rates
+)
+
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Name      string         `json:"name"`
-	Email     string         `gorm:"unique" json:"email"`
-	Password  string         `json:"-"` // hide from JSON response
-	Items     []Item         `gorm:"foreignKey:UserID" json:"items,omitempty"`
-	Locations []Location     `gorm:"foreignKey:UserID" json:"locations,omitempty"` // personalized locations
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	Name             string         `json:"name"`
+	Email            string         `gorm:"unique" json:"email"`
+	Password         string         `json:"-"` // hide from JSON response
+	Role             string         `gorm:"default:user" json:"role"`
+	EmailVerifiedAt  *time.Time     `json:"email_verified_at"`
+	MFAEnabledAt     *time.Time     `json:"mfa_enabled_at"` // set once TOTP enrolment is confirmed
+	MFASecret        string         `json:"-"`              // base32 TOTP secret
+	MFAPendingSecret string         `json:"-"`              // secret awaiting confirmation during enrolment
+	MFALastCounter   uint64         `json:"-"`              // last accepted TOTP time step, so codes can't be replayed
+	Items            []Item         `gorm:"foreignKey:UserID" json:"items,omitempty"`
+	Locations        []Location     `gorm:"foreignKey:UserID" json:"locations,omitempty"` // personalized locations
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 type Item struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	Name               string     `json:"name"`
+	Description        string     `json:"description"`
+	UserID             uint       `json:"user_id"`
+	User               User       `gorm:"foreignKey:UserID" json:"-"`
+	LocationID         uint       `json:"location_id"`
+	Location           Location   `gorm:"foreignKey:LocationID" json:"location,omitempty"`
+	ImageUrl           string     `json:"image_url"`
+	Quantity           int        `binding:"min=0" json:"quantity"`
+	MinQuantity        int        `binding:"min=0" json:"min_quantity"`     // reorder point: alert when quantity falls to this level
+	ReorderQuantity    int        `binding:"min=0" json:"reorder_quantity"` // how many to buy when restocking
+	PreferredSupplier  string     `json:"preferred_supplier"`               // free-text supplier name, used when no supplier is linked
+	SupplierID         *uint      `gorm:"index" json:"supplier_id"`
+	Supplier           *Supplier  `gorm:"foreignKey:SupplierID" json:"supplier,omitempty"`
+	Lots               []Lot      `gorm:"foreignKey:ItemID" json:"lots,omitempty"` // batches with expiry dates; quantities sum to Quantity
+	CategoryID         *uint      `gorm:"index" json:"category_id"`
+	Category           *Category  `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
+	Tags               []Tag      `gorm:"many2many:item_tags" json:"tags,omitempty"`
+	CustomFields       JSONMap    `gorm:"type:jsonb" json:"custom_fields,omitempty"` // values for the category's CustomField definitions
+	PurchasePrice      float64    `binding:"min=0" json:"purchase_price"`            // unit cost, used for incoming stock movements
+	Currency           string     `gorm:"size:3" json:"currency"`
+	PurchaseDate       *time.Time `json:"purchase_date"`
+	Vendor             string     `json:"vendor"`
+	ReceiptUrl         string     `json:"receipt_url"` // set by the receipt upload endpoint
+	ReceiptPath        string     `json:"-"`           // where the uploaded receipt is stored on disk
+	Barcode            string     `gorm:"index" json:"barcode"`
+	DepreciationMethod string     `binding:"omitempty,oneof=none straight_line declining_balance" json:"depreciation_method"`
+	UsefulLifeMonths   int        `binding:"min=0" json:"useful_life_months"`
+	SalvageValue       float64    `binding:"min=0" json:"salvage_value"`     // per-unit value at the end of the useful life
+	DepreciationRate   float64    `binding:"min=0" json:"depreciation_rate"` // yearly rate for declining balance (default double-declining)
+	CurrentValue       float64    `gorm:"-" json:"current_value"`            // per-unit value today, computed after loading
+	ReservedQuantity   int        `json:"reserved_quantity"`                 // held by active reservations
+	AvailableQuantity  int        `gorm:"-" json:"available_quantity"`       // on hand minus reserved, computed after loading
+	WarrantyProvider   string     `json:"warranty_provider"`
+	WarrantyStartsAt   *time.Time `json:"warranty_starts_at"`
+	WarrantyEndsAt     *time.Time `gorm:"index" json:"warranty_ends_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+type Location struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
-	UserID      uint      `json:"user_id"`
-	User        User      `gorm:"foreignKey:UserID" json:"-"`
-	LocationID  uint      `json:"location_id"`
-	Location    Location  `gorm:"foreignKey:LocationID" json:"location,omitempty"`
 	ImageUrl    string    `json:"image_url"`
+	UserID      uint      `json:"user_id"`                    // associates the location with a user
+	User        User      `gorm:"foreignKey:UserID" json:"-"` // optional: hide user details in JSON if needed
+	ParentID    *uint     `gorm:"index" json:"parent_id"`     // enclosing location, e.g. the room a shelf is in
+	Items       []Item    `gorm:"foreignKey:LocationID" json:"items,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
-
-type Location struct {
-	ID          uint   `gorm:"primaryKey" json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	ImageUrl    string `json:"image_url"`
-	UserID      uint   `json:"user_id"`                    // associates the location with a user
-	User        User   `gorm:"foreignKey:UserID" json:"-"` // optional: hide user details in JSON if needed
-	Items       []Item `gorm:"foreignKey:LocationID" json:"items,omitempty"`
-}
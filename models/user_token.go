@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// User token purposes
+const (
+	TokenEmailVerification = "email_verification"
+	TokenPasswordReset     = "password_reset"
+)
+
+// UserToken is a single-use, time-limited token emailed to a user. Only the SHA-256
+// hash of the token is stored, so a database leak can't be used to take over accounts.
+type UserToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	Purpose   string     `gorm:"index" json:"purpose"`
+	TokenHash string     `gorm:"uniqueIndex" json:"-"`
+	Email     string     `json:"email"` // address the token was sent to
+	SessionID uint       `json:"-"`     // for email changes, the session that asked, which stays signed in
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,119 @@
+// passwords/argon2.go
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Default argon2id parameters, following the OWASP recommendation of 19 MiB, 2 passes, 1 lane
+const (
+	DefaultArgon2Memory      = 19 * 1024 // KiB
+	DefaultArgon2Iterations  = 2
+	DefaultArgon2Parallelism = 1
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// Argon2idHasher hashes with argon2id. Zero parameters mean the defaults.
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// argon2Params are the parameters read back from an encoded hash
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+// Hash returns a PHC-format argon2id hash with a random salt
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	memory, iterations, parallelism := h.params()
+	key := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, memory, iterations, parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify recomputes the hash with the encoded parameters and compares in constant time
+func (h *Argon2idHasher) Verify(password string, encoded string) (bool, error) {
+	params, err := decodeArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+// Handles reports whether the hash is an argon2id PHC string
+func (h *Argon2idHasher) Handles(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// NeedsRehash reports whether the hash was made with other parameters
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, err := decodeArgon2(encoded)
+	if err != nil {
+		return true
+	}
+	memory, iterations, parallelism := h.params()
+	return params.memory != memory || params.iterations != iterations || params.parallelism != parallelism ||
+		len(params.salt) != argon2SaltLength || len(params.key) != argon2KeyLength
+}
+
+func (h *Argon2idHasher) params() (uint32, uint32, uint8) {
+	memory, iterations, parallelism := h.Memory, h.Iterations, h.Parallelism
+	if memory == 0 {
+		memory = DefaultArgon2Memory
+	}
+	if iterations == 0 {
+		iterations = DefaultArgon2Iterations
+	}
+	if parallelism == 0 {
+		parallelism = DefaultArgon2Parallelism
+	}
+	return memory, iterations, parallelism
+}
+
+// decodeArgon2 parses "$argon2id$v=19$m=...,t=...,p=...$salt$key"
+func decodeArgon2(encoded string) (argon2Params, error) {
+	var params argon2Params
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, fmt.Errorf("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+	if params.memory == 0 || params.iterations == 0 || params.parallelism == 0 {
+		return params, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, fmt.Errorf("invalid argon2 salt")
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return params, fmt.Errorf("invalid argon2 hash")
+	}
+	return params, nil
+}
@@ -0,0 +1,52 @@
+// passwords/bcrypt.go
+package passwords
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultBcryptCost is the cost used when none is configured
+const DefaultBcryptCost = bcrypt.DefaultCost
+
+// bcryptMaxBytes is the longest password bcrypt accepts
+const bcryptMaxBytes = 72
+
+// BcryptHasher hashes with bcrypt. A zero Cost means the default.
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash returns a bcrypt hash. bcrypt refuses passwords over 72 bytes; CheckLength catches them first.
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost())
+	return string(hash), err
+}
+
+// Verify compares a password with a bcrypt hash
+func (h *BcryptHasher) Verify(password string, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Handles reports whether the hash is a bcrypt hash
+func (h *BcryptHasher) Handles(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// NeedsRehash reports whether the hash was made with another cost
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.cost()
+}
+
+func (h *BcryptHasher) cost() int {
+	if h.Cost == 0 {
+		return DefaultBcryptCost
+	}
+	return h.Cost
+}
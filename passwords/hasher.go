@@ -0,0 +1,99 @@
+// passwords/hasher.go
+package passwords
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Hasher turns passwords into self-describing hashes. Argon2idHasher writes PHC strings such
+// as "$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>"; BcryptHasher writes the usual "$2a$" form.
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(password string, encoded string) (bool, error)
+	// Handles reports whether an encoded hash is in this hasher's format
+	Handles(encoded string) bool
+	// NeedsRehash reports whether a hash in this format was made with other parameters
+	NeedsRehash(encoded string) bool
+}
+
+var (
+	mu      sync.Mutex
+	current Hasher
+)
+
+// Get returns the configured hasher, building it from the environment on first use
+func Get() Hasher {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		current = FromEnv()
+	}
+	return current
+}
+
+// Set replaces the hasher, e.g. with cheaper parameters
+func Set(hasher Hasher) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = hasher
+}
+
+// FromEnv builds the hasher selected by PASSWORD_HASHER (argon2id or bcrypt; default argon2id).
+// ARGON2_MEMORY_KB, ARGON2_ITERATIONS, ARGON2_PARALLELISM and BCRYPT_COST tune the parameters.
+func FromEnv() Hasher {
+	switch strings.ToLower(os.Getenv("PASSWORD_HASHER")) {
+	case "bcrypt":
+		return &BcryptHasher{Cost: intFromEnv("BCRYPT_COST", DefaultBcryptCost)}
+	case "", "argon2id":
+	default:
+		fmt.Printf("Unknown PASSWORD_HASHER %q, using argon2id\n", os.Getenv("PASSWORD_HASHER"))
+	}
+	return &Argon2idHasher{
+		Memory:      uint32(intFromEnv("ARGON2_MEMORY_KB", DefaultArgon2Memory)),
+		Iterations:  uint32(intFromEnv("ARGON2_ITERATIONS", DefaultArgon2Iterations)),
+		Parallelism: uint8(intFromEnv("ARGON2_PARALLELISM", DefaultArgon2Parallelism)),
+	}
+}
+
+// Hash hashes a password with the configured hasher
+func Hash(password string) (string, error) {
+	return Get().Hash(password)
+}
+
+// Verify checks a password against a hash in any supported format. needsRehash is true when
+// the password matched but the hash isn't what the configured hasher would produce today, so
+// the caller should store a fresh Hash of the password.
+func Verify(password string, encoded string) (match bool, needsRehash bool, err error) {
+	hasher := Get()
+	if hasher.Handles(encoded) {
+		match, err = hasher.Verify(password, encoded)
+		return match, match && hasher.NeedsRehash(encoded), err
+	}
+
+	// Hashes from a previously configured algorithm still verify, then get upgraded
+	for _, other := range []Hasher{&Argon2idHasher{}, &BcryptHasher{}} {
+		if other.Handles(encoded) {
+			match, err = other.Verify(password, encoded)
+			return match, match, err
+		}
+	}
+	return false, false, fmt.Errorf("unrecognised password hash format")
+}
+
+// intFromEnv reads a positive integer from the environment, falling back to a default
+func intFromEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		fmt.Printf("Invalid %s %q, using %d\n", key, value, fallback)
+		return fallback
+	}
+	return number
+}
@@ -0,0 +1,183 @@
+// passwords/policy.go
+package passwords
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commonPasswords are always rejected, with or without a breached-password list
+var commonPasswords = []string{
+	"password", "password1", "password123", "passw0rd", "12345678", "123456789", "1234567890",
+	"qwerty123", "qwertyuiop", "1q2w3e4r", "iloveyou", "sunshine", "princess", "football",
+	"baseball", "welcome1", "letmein1", "admin123", "abc12345", "11111111", "00000000",
+	"inventory", "inventory1",
+}
+
+// PolicyError explains why a password was rejected; the message is safe to show to users
+type PolicyError struct {
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return "password " + e.Reason
+}
+
+// Policy is what a new password has to satisfy
+type Policy struct {
+	MinLength  int                 // in characters
+	MaxLength  int                 // in characters, bounding the work done hashing
+	MinClasses int                 // how many of lower case, upper case, digits and symbols must appear
+	Breached   map[string]struct{} // upper-case SHA-1 hex of known breached passwords
+}
+
+var (
+	policyOnce    sync.Once
+	currentPolicy *Policy
+)
+
+// CurrentPolicy returns the policy configured by PASSWORD_MIN_LENGTH (default 8),
+// PASSWORD_MAX_LENGTH (default 128), PASSWORD_MIN_CLASSES (default 1) and
+// PASSWORD_BREACHED_LIST, a file of breached passwords loaded once at first use
+func CurrentPolicy() *Policy {
+	policyOnce.Do(func() {
+		currentPolicy = &Policy{
+			MinLength:  intFromEnv("PASSWORD_MIN_LENGTH", 8),
+			MaxLength:  intFromEnv("PASSWORD_MAX_LENGTH", 128),
+			MinClasses: intFromEnv("PASSWORD_MIN_CLASSES", 1),
+			Breached:   make(map[string]struct{}),
+		}
+		for _, password := range commonPasswords {
+			currentPolicy.Breached[sha1Hex(password)] = struct{}{}
+		}
+		if path := os.Getenv("PASSWORD_BREACHED_LIST"); path != "" {
+			count, err := currentPolicy.LoadBreachedList(path)
+			if err != nil {
+				fmt.Printf("Error loading breached password list %s: %v\n", path, err)
+			} else {
+				fmt.Printf("Loaded %d breached passwords from %s\n", count, path)
+			}
+		}
+	})
+	return currentPolicy
+}
+
+// Check validates a password against the configured policy
+func Check(password string, userInputs ...string) error {
+	return CurrentPolicy().Check(password, userInputs...)
+}
+
+// Check validates a password. userInputs are things like the email and name, which the
+// password may not simply repeat.
+func (p *Policy) Check(password string, userInputs ...string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return &PolicyError{fmt.Sprintf("must be at least %d characters", p.MinLength)}
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return &PolicyError{fmt.Sprintf("must be at most %d characters", p.MaxLength)}
+	}
+	if err := CheckLength(password); err != nil {
+		return err
+	}
+
+	if classes := characterClasses(password); classes < p.MinClasses {
+		return &PolicyError{fmt.Sprintf("must mix at least %d of lower case letters, upper case letters, digits and symbols", p.MinClasses)}
+	}
+
+	lower := strings.ToLower(password)
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		localPart, _, _ := strings.Cut(input, "@")
+		if input != "" && (lower == input || lower == localPart) {
+			return &PolicyError{"must not be your email address or name"}
+		}
+	}
+
+	if _, found := p.Breached[sha1Hex(password)]; found {
+		return &PolicyError{"appears in a list of breached or common passwords, please choose another"}
+	}
+	if _, found := p.Breached[sha1Hex(lower)]; found {
+		return &PolicyError{"appears in a list of breached or common passwords, please choose another"}
+	}
+	return nil
+}
+
+// CheckLength rejects passwords too long for the configured hasher to take in full.
+// bcrypt stops at 72 bytes, which multi-byte characters can reach well within MaxLength.
+func CheckLength(password string) error {
+	if _, ok := Get().(*BcryptHasher); ok && len(password) > bcryptMaxBytes {
+		return &PolicyError{fmt.Sprintf("must be at most %d bytes", bcryptMaxBytes)}
+	}
+	return nil
+}
+
+// LoadBreachedList adds the passwords in a file, one per line. Lines may be plain passwords or
+// SHA-1 hashes in the Have I Been Pwned "HASH:count" format. Blank lines and # comments are skipped.
+func (p *Policy) LoadBreachedList(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if hash, _, _ := strings.Cut(line, ":"); isSHA1Hex(hash) {
+			p.Breached[strings.ToUpper(hash)] = struct{}{}
+		} else {
+			p.Breached[sha1Hex(line)] = struct{}{}
+		}
+		count++
+	}
+	return count, scanner.Err()
+}
+
+// characterClasses counts which of lower case, upper case, digits and symbols appear
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	count := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+func sha1Hex(value string) string {
+	sum := sha1.Sum([]byte(value))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isSHA1Hex(value string) bool {
+	if len(value) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
@@ -0,0 +1,330 @@
+// routes/account.go
+package routes
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/mailer"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"github.com/sidhant-sriv/inventory-api/passwords"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// How long emailed tokens stay valid
+const (
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+)
+
+// errInvalidUserToken is returned for unknown, expired or already used tokens
+var errInvalidUserToken = fmt.Errorf("invalid or expired token")
+
+// errEmailTaken is returned when a verified email change collides with another account
+var errEmailTaken = fmt.Errorf("email is already taken")
+
+// RequestEmailVerification emails the authenticated user a new verification link
+func RequestEmailVerification() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := findCurrentUser(c)
+		if !ok {
+			return
+		}
+		if user.EmailVerifiedAt != nil {
+			c.JSON(http.StatusOK, gin.H{"message": "Email is already verified"})
+			return
+		}
+
+		if err := sendVerificationEmail(db.GetDB(), user); err != nil {
+			fmt.Printf("Error issuing verification token for user ID %d: %v\n", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+	}
+}
+
+// VerifyEmail marks a user's email as verified using the token from the verification email.
+// A link sent to a new address during an email change switches the account to that address.
+func VerifyEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var verifyRequest struct {
+			Token string `json:"token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&verifyRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			token, err := consumeUserToken(tx, models.TokenEmailVerification, verifyRequest.Token)
+			if err != nil {
+				return err
+			}
+			var user models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, token.UserID).Error; err != nil {
+				return errInvalidUserToken
+			}
+			if user.Email != token.Email {
+				// The owner of the new address has just proved it, so telling them it was
+				// taken in the meantime reveals nothing
+				var taken int64
+				if err := tx.Unscoped().Model(&models.User{}).Where("LOWER(email) = LOWER(?) AND id != ?", token.Email, user.ID).Count(&taken).Error; err != nil {
+					return err
+				}
+				if taken > 0 {
+					return errEmailTaken
+				}
+				// Only the session that asked for the change stays signed in
+				if err := models.RevokeOtherUserSessions(tx, user.ID, token.SessionID); err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&user).Updates(map[string]interface{}{"email": token.Email, "email_verified_at": time.Now()}).Error; err != nil {
+				return err
+			}
+			return claimInvitations(tx, user.ID, token.Email)
+		})
+		if err == errInvalidUserToken {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+		if err == errEmailTaken {
+			c.JSON(http.StatusConflict, gin.H{"error": "This email address is already in use by another account"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+	}
+}
+
+// claimInvitations hands stocktake invitations and shares sent to an email address to the
+// account that has just verified it, skipping any it already has
+func claimInvitations(tx *gorm.DB, userID uint, email string) error {
+	if err := tx.Model(&models.StocktakeCounter{}).
+		Where("user_id IS NULL AND LOWER(email) = LOWER(?)", email).
+		Where("session_id NOT IN (?)", tx.Model(&models.StocktakeCounter{}).Select("session_id").Where("user_id = ?", userID)).
+		Update("user_id", userID).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Share{}).
+		Where("user_id IS NULL AND LOWER(email) = LOWER(?) AND owner_id != ?", email, userID).
+		Where("NOT EXISTS (?)", tx.Table("shares AS claimed").Select("1").
+			Where("claimed.user_id = ? AND (claimed.location_id = shares.location_id OR claimed.item_id = shares.item_id)", userID)).
+		Update("user_id", userID).Error
+}
+
+// ForgotPassword emails a password-reset link. The response is the same whether or not
+// the email is registered, and the lookup runs in the background so response times don't
+// reveal it either.
+func ForgotPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var forgotRequest struct {
+			Email string `json:"email" binding:"required,email"`
+		}
+		if err := c.ShouldBindJSON(&forgotRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		go sendPasswordReset(db.GetDB(), forgotRequest.Email)
+
+		c.JSON(http.StatusOK, gin.H{"message": "If that email is registered, a reset link has been sent"})
+	}
+}
+
+// sendPasswordReset emails a reset link if the address belongs to an account
+func sendPasswordReset(DB *gorm.DB, email string) {
+	var user models.User
+	if err := DB.Where("email = ?", email).First(&user).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			fmt.Printf("Database error during password reset lookup: %v\n", err)
+		}
+		return
+	}
+	token, err := issueUserToken(DB, user, models.TokenPasswordReset, passwordResetTTL)
+	if err != nil {
+		fmt.Printf("Error issuing password reset token for user ID %d: %v\n", user.ID, err)
+		return
+	}
+	sendEmail(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password for your inventory account. "+
+			"If it was you, use this link within %s:\n\n%s\n\nIf it wasn't, you can ignore this email.\n",
+			user.Name, passwordResetTTL, accountLink("reset-password", token)),
+	})
+}
+
+// ResetPassword sets a new password using the token from the reset email
+func ResetPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var resetRequest struct {
+			Token    string `json:"token" binding:"required"`
+			Password string `json:"password" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&resetRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			token, err := consumeUserToken(tx, models.TokenPasswordReset, resetRequest.Token)
+			if err != nil {
+				return err
+			}
+			var user models.User
+			if err := tx.First(&user, token.UserID).Error; err != nil {
+				return errInvalidUserToken
+			}
+			// A rejected password rolls back, leaving the token usable
+			if err := passwords.Check(resetRequest.Password, user.Email, user.Name); err != nil {
+				return err
+			}
+			hashedPassword, err := passwords.Hash(resetRequest.Password)
+			if err != nil {
+				return err
+			}
+
+			// Following the emailed link proves the user controls the address
+			updates := map[string]interface{}{"password": hashedPassword}
+			if user.EmailVerifiedAt == nil && user.Email == token.Email {
+				updates["email_verified_at"] = time.Now()
+				if err := claimInvitations(tx, user.ID, user.Email); err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&user).Updates(updates).Error; err != nil {
+				return err
+			}
+			// Whoever knew the old password is signed out everywhere
+			if err := models.RevokeUserSessions(tx, user.ID); err != nil {
+				return err
+			}
+			// Any other outstanding reset links stop working
+			return tx.Model(&models.UserToken{}).
+				Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, models.TokenPasswordReset).
+				Update("used_at", time.Now()).Error
+		})
+		if err == errInvalidUserToken {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+		var policyErr *passwords.PolicyError
+		if errors.As(err, &policyErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + policyErr.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+	}
+}
+
+// sendVerificationEmail issues a verification token for the user's current email and sends it
+func sendVerificationEmail(DB *gorm.DB, user models.User) error {
+	token, err := issueUserToken(DB, user, models.TokenEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	sendEmail(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm this is your email address by opening this link within %s:\n\n%s\n",
+			user.Name, emailVerificationTTL, accountLink("verify-email", token)),
+	})
+	return nil
+}
+
+// sendEmail delivers a message in the background so slow mail servers don't hold up
+// requests (and response times don't reveal whether an account exists)
+func sendEmail(message mailer.Message) {
+	go func() {
+		if err := mailer.Get().Send(message); err != nil {
+			fmt.Printf("Error sending email %q: %v\n", message.Subject, err)
+		}
+	}()
+}
+
+// accountLink builds the link put in account emails. APP_URL is the front end that
+// handles the page; without it the raw token is sent so it can be posted to the API.
+func accountLink(page string, token string) string {
+	appURL := strings.TrimSuffix(os.Getenv("APP_URL"), "/")
+	if appURL == "" {
+		return "Token: " + token
+	}
+	return fmt.Sprintf("%s/%s?token=%s", appURL, page, token)
+}
+
+// issueUserToken creates a random token for the user, storing only its hash.
+// Earlier unused tokens for the same purpose are invalidated.
+func issueUserToken(DB *gorm.DB, user models.User, purpose string, ttl time.Duration) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserToken{
+			UserID:    user.ID,
+			Purpose:   purpose,
+			TokenHash: hashUserToken(token),
+			Email:     user.Email,
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	return token, err
+}
+
+// consumeUserToken looks up an unused, unexpired token and marks it used
+func consumeUserToken(tx *gorm.DB, purpose string, token string) (models.UserToken, error) {
+	var userToken models.UserToken
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ?", hashUserToken(token), purpose).
+		First(&userToken).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return userToken, errInvalidUserToken
+		}
+		return userToken, err
+	}
+	if userToken.UsedAt != nil || time.Now().After(userToken.ExpiresAt) {
+		return userToken, errInvalidUserToken
+	}
+
+	now := time.Now()
+	userToken.UsedAt = &now
+	return userToken, tx.Model(&userToken).Update("used_at", now).Error
+}
+
+// hashUserToken is the SHA-256 of a token; tokens are random so no salt is needed
+func hashUserToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
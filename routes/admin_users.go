@@ -0,0 +1,223 @@
+// routes/admin_users.go
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// adminUserSortColumns are the columns the user directory can be sorted by
+var adminUserSortColumns = map[string]bool{"email": true, "name": true, "created_at": true}
+
+// adminUser is a user as shown in the administrator directory
+type adminUser struct {
+	ID              uint       `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Role            string     `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	MFAEnabledAt    *time.Time `json:"mfa_enabled_at"`
+	LockedUntil     *time.Time `json:"locked_until"`
+	CreatedAt       time.Time  `json:"created_at"`
+	DeletedAt       *time.Time `json:"deleted_at"`
+}
+
+// AdminUserRoutes sets up the administrator user directory
+func AdminUserRoutes(router *gin.Engine) {
+	adminRoutes := router.Group("/admin/users")
+	adminRoutes.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	{
+		adminRoutes.GET("/", SearchUsers())
+		adminRoutes.GET("/:user_id", GetAdminUser())
+	}
+}
+
+// SearchUsers lists users for administrators. Filters:
+//   - q: email or name prefix (case-insensitive); email and name filter on one field each
+//   - status: active (default), verified, unverified, locked, mfa, deleted or all
+//   - role: user or admin
+//   - created_after, created_before: YYYY-MM-DD, inclusive
+//   - sort: email (default), name or created_at, with a leading "-" for descending
+//   - page, page_size (1-100, default 20)
+func SearchUsers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if err != nil || page < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page parameter"})
+			return
+		}
+		pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+		if err != nil || pageSize < 1 || pageSize > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page_size parameter (must be 1-100)"})
+			return
+		}
+
+		DB := db.GetDB()
+		query := DB.Model(&models.User{})
+		now := time.Now()
+		switch status := c.DefaultQuery("status", "active"); status {
+		case "active":
+		case "verified":
+			query = query.Where("email_verified_at IS NOT NULL")
+		case "unverified":
+			query = query.Where("email_verified_at IS NULL")
+		case "mfa":
+			query = query.Where("mfa_enabled_at IS NOT NULL")
+		case "locked":
+			query = query.Where("EXISTS (SELECT 1 FROM login_throttles WHERE login_throttles.key = 'email:' || LOWER(users.email) AND login_throttles.locked_until > ?)", now)
+		case "deleted":
+			query = query.Unscoped().Where("users.deleted_at IS NOT NULL")
+		case "all":
+			query = query.Unscoped()
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status: use active, verified, unverified, locked, mfa, deleted or all"})
+			return
+		}
+
+		if q := c.Query("q"); q != "" {
+			prefix := likePrefix(q)
+			query = query.Where("(LOWER(email) LIKE ? OR LOWER(name) LIKE ?)", prefix, prefix)
+		}
+		if email := c.Query("email"); email != "" {
+			query = query.Where("LOWER(email) LIKE ?", likePrefix(email))
+		}
+		if name := c.Query("name"); name != "" {
+			query = query.Where("LOWER(name) LIKE ?", likePrefix(name))
+		}
+		if role := c.Query("role"); role != "" {
+			if role != models.RoleUser && role != models.RoleAdmin {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role: use user or admin"})
+				return
+			}
+			query = query.Where("role = ?", role)
+		}
+		if value := c.Query("created_after"); value != "" {
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid created_after date (use YYYY-MM-DD)"})
+				return
+			}
+			query = query.Where("users.created_at >= ?", date)
+		}
+		if value := c.Query("created_before"); value != "" {
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid created_before date (use YYYY-MM-DD)"})
+				return
+			}
+			query = query.Where("users.created_at < ?", date.AddDate(0, 0, 1))
+		}
+
+		sort := c.DefaultQuery("sort", "email")
+		direction := "ASC"
+		if strings.HasPrefix(sort, "-") {
+			sort, direction = strings.TrimPrefix(sort, "-"), "DESC"
+		}
+		if !adminUserSortColumns[sort] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort: use email, name or created_at"})
+			return
+		}
+
+		// The filtered query is used twice, for the count and the page
+		query = query.Session(&gorm.Session{})
+		var total int64
+		if result := query.Count(&total); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count users: " + result.Error.Error()})
+			return
+		}
+		var users []models.User
+		if result := query.Order("users." + sort + " " + direction).Order("users.id").
+			Offset((page - 1) * pageSize).Limit(pageSize).Find(&users); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users: " + result.Error.Error()})
+			return
+		}
+
+		results, err := adminUsers(DB, users)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve lockouts: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"users":       results,
+			"total":       total,
+			"page":        page,
+			"page_size":   pageSize,
+			"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		})
+	}
+}
+
+// GetAdminUser shows one user, including deleted ones, to an administrator
+func GetAdminUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		DB := db.GetDB()
+		if result := DB.Unscoped().First(&user, c.Param("user_id")); result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user: " + result.Error.Error()})
+			}
+			return
+		}
+
+		results, err := adminUsers(DB, []models.User{user})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve lockout: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"user": results[0]})
+	}
+}
+
+// adminUsers converts users for the directory, adding any current lockout
+func adminUsers(DB *gorm.DB, users []models.User) ([]adminUser, error) {
+	keys := make([]string, len(users))
+	for i, user := range users {
+		keys[i] = models.EmailThrottleKey(user.Email)
+	}
+	lockedUntil := make(map[string]*time.Time)
+	if len(keys) > 0 {
+		var throttles []models.LoginThrottle
+		if err := DB.Where("key IN ? AND locked_until > ?", keys, time.Now()).Find(&throttles).Error; err != nil {
+			return nil, err
+		}
+		for _, throttle := range throttles {
+			lockedUntil[throttle.Key] = throttle.LockedUntil
+		}
+	}
+
+	results := make([]adminUser, len(users))
+	for i, user := range users {
+		results[i] = adminUser{
+			ID:              user.ID,
+			Name:            user.Name,
+			Email:           user.Email,
+			Role:            user.Role,
+			EmailVerifiedAt: user.EmailVerifiedAt,
+			MFAEnabledAt:    user.MFAEnabledAt,
+			LockedUntil:     lockedUntil[keys[i]],
+			CreatedAt:       user.CreatedAt,
+		}
+		if user.DeletedAt.Valid {
+			deletedAt := user.DeletedAt.Time
+			results[i].DeletedAt = &deletedAt
+		}
+	}
+	return results, nil
+}
+
+// likePrefix turns user input into a case-insensitive LIKE prefix pattern, escaping wildcards
+func likePrefix(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(value))
+	return value + "%"
+}
@@ -0,0 +1,238 @@
+// routes/alerts.go
+package routes
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// AlertRoutes sets up the routes for stock alerts and the shopping list
+func AlertRoutes(router *gin.Engine) {
+	alertRoutes := router.Group("/alerts")
+	alertRoutes.Use(middleware.AuthMiddleware())
+	{
+		alertRoutes.GET("/", GetAlerts())
+		alertRoutes.GET("/shopping-list", GetShoppingList())
+		alertRoutes.POST("/:alert_id/acknowledge", AcknowledgeAlert())
+		alertRoutes.POST("/:alert_id/snooze", SnoozeAlert())
+	}
+}
+
+// GetAlerts lists the authenticated user's unresolved alerts.
+// Snoozed alerts are hidden unless include_snoozed=true; status and type filter the list.
+func GetAlerts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		DB := db.GetDB()
+		query := DB.Preload("Item").Where("user_id = ?", userID)
+
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		} else {
+			query = query.Where("status <> ?", models.AlertResolved)
+		}
+		if alertType := c.Query("type"); alertType != "" {
+			query = query.Where("type = ?", alertType)
+		}
+		if c.Query("include_snoozed") != "true" {
+			query = query.Where("snoozed_until IS NULL OR snoozed_until <= ?", time.Now())
+		}
+
+		var alerts []models.Alert
+		if result := query.Order("created_at DESC").Find(&alerts); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alerts: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+	}
+}
+
+// AcknowledgeAlert marks an alert as seen; it stays listed until the underlying condition clears
+func AcknowledgeAlert() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alert, ok := findUserAlert(c)
+		if !ok {
+			return
+		}
+		if alert.Status == models.AlertResolved {
+			c.JSON(http.StatusConflict, gin.H{"error": "Alert is already resolved"})
+			return
+		}
+
+		now := time.Now()
+		alert.Status = models.AlertAcknowledged
+		alert.AcknowledgedAt = &now
+
+		DB := db.GetDB()
+		if result := DB.Save(&alert); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge alert: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"alert": alert})
+	}
+}
+
+// SnoozeAlert hides an alert until the given time (or for the given duration, e.g. "24h")
+func SnoozeAlert() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var snoozeRequest struct {
+			Until    *time.Time `json:"until"`
+			Duration string     `json:"duration"`
+		}
+		if err := c.ShouldBindJSON(&snoozeRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		var until time.Time
+		switch {
+		case snoozeRequest.Until != nil:
+			until = *snoozeRequest.Until
+		case snoozeRequest.Duration != "":
+			duration, err := time.ParseDuration(snoozeRequest.Duration)
+			if err != nil || duration <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration. Use a Go duration such as 2h or 72h"})
+				return
+			}
+			until = time.Now().Add(duration)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Either until or duration is required"})
+			return
+		}
+		if !until.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Snooze time must be in the future"})
+			return
+		}
+
+		alert, ok := findUserAlert(c)
+		if !ok {
+			return
+		}
+		if alert.Status == models.AlertResolved {
+			c.JSON(http.StatusConflict, gin.H{"error": "Alert is already resolved"})
+			return
+		}
+		alert.SnoozedUntil = &until
+
+		DB := db.GetDB()
+		if result := DB.Save(&alert); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to snooze alert: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"alert": alert})
+	}
+}
+
+// shoppingListEntry is a single item to reorder
+type shoppingListEntry struct {
+	ItemID        uint   `json:"item_id"`
+	Name          string `json:"name"`
+	Quantity      int    `json:"quantity"`
+	MinQuantity   int    `json:"min_quantity"`
+	OrderQuantity int    `json:"order_quantity"`
+	LocationID    uint   `json:"location_id"`
+}
+
+// GetShoppingList builds a reorder list of low items grouped by supplier: the linked supplier
+// if there is one, otherwise the free-text preferred supplier
+func GetShoppingList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		var items []models.Item
+		DB := db.GetDB()
+		if result := DB.Preload("Supplier").Where("user_id = ? AND min_quantity > 0 AND quantity <= min_quantity", userID).Order("name").Find(&items); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve items: " + result.Error.Error()})
+			return
+		}
+
+		groups := make(map[string][]shoppingListEntry)
+		supplierIDs := make(map[string]uint)
+		for _, item := range items {
+			// Fall back to topping the item up just above its reorder point
+			orderQuantity := item.ReorderQuantity
+			if orderQuantity <= 0 {
+				orderQuantity = item.MinQuantity - item.Quantity + 1
+			}
+
+			supplier := item.PreferredSupplier
+			if item.Supplier != nil {
+				supplier = item.Supplier.Name
+				supplierIDs[supplier] = item.Supplier.ID
+			}
+			if supplier == "" {
+				supplier = "Unassigned"
+			}
+			groups[supplier] = append(groups[supplier], shoppingListEntry{
+				ItemID:        item.ID,
+				Name:          item.Name,
+				Quantity:      item.Quantity,
+				MinQuantity:   item.MinQuantity,
+				OrderQuantity: orderQuantity,
+				LocationID:    item.LocationID,
+			})
+		}
+
+		suppliers := make([]string, 0, len(groups))
+		for supplier := range groups {
+			suppliers = append(suppliers, supplier)
+		}
+		sort.Strings(suppliers)
+
+		shoppingList := make([]gin.H, 0, len(suppliers))
+		for _, supplier := range suppliers {
+			entry := gin.H{
+				"supplier": supplier,
+				"items":    groups[supplier],
+			}
+			if id, linked := supplierIDs[supplier]; linked {
+				entry["supplier_id"] = id
+			}
+			shoppingList = append(shoppingList, entry)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"shopping_list": shoppingList})
+	}
+}
+
+// findUserAlert loads the alert named in the URL if it belongs to the authenticated user.
+// It writes the error response itself and reports whether the handler should continue.
+func findUserAlert(c *gin.Context) (models.Alert, bool) {
+	var alert models.Alert
+
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return alert, false
+	}
+
+	DB := db.GetDB()
+	if result := DB.Where("id = ? AND user_id = ?", c.Param("alert_id"), userID).First(&alert); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alert: " + result.Error.Error()})
+		}
+		return alert, false
+	}
+	return alert, true
+}
@@ -0,0 +1,185 @@
+// routes/api_keys.go
+package routes
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// APIKeyRoutes sets up the routes for managing personal API keys. Keys can't be used
+// on these routes themselves; managing keys needs a normal login.
+func APIKeyRoutes(router *gin.Engine) {
+	apiKeyRoutes := router.Group("/api-keys")
+	apiKeyRoutes.Use(middleware.AuthMiddleware())
+	{
+		apiKeyRoutes.GET("/", GetAPIKeys())
+		apiKeyRoutes.POST("/", CreateAPIKey())
+		apiKeyRoutes.GET("/:key_id", GetAPIKey())
+		apiKeyRoutes.DELETE("/:key_id", RevokeAPIKey())
+	}
+}
+
+// GetAPIKeys lists the user's API keys. Revoked keys are included with include_revoked=true.
+func GetAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		DB := db.GetDB()
+		query := DB.Where("user_id = ?", userID)
+		if c.Query("include_revoked") != "true" {
+			query = query.Where("revoked_at IS NULL")
+		}
+		var keys []models.APIKey
+		if result := query.Order("created_at DESC").Find(&keys); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API keys: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+	}
+}
+
+// CreateAPIKey issues a new API key. The key itself is only returned in this response.
+func CreateAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		var keyRequest struct {
+			Name      string     `json:"name" binding:"required"`
+			Scopes    []string   `json:"scopes" binding:"required,min=1"`
+			ExpiresAt *time.Time `json:"expires_at"`
+		}
+		if err := c.ShouldBindJSON(&keyRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+		for _, scope := range keyRequest.Scopes {
+			if !models.ValidScope(scope) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":     fmt.Sprintf("Invalid scope %q: use <resource>:read, <resource>:write or <resource>:admin", scope),
+					"resources": append([]string{"*"}, models.APIKeyResources...),
+				})
+				return
+			}
+		}
+		if keyRequest.ExpiresAt != nil && !keyRequest.ExpiresAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+			return
+		}
+
+		rawKey, prefix, err := generateAPIKey()
+		if err != nil {
+			fmt.Printf("Error generating API key: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+			return
+		}
+		key := models.APIKey{
+			UserID:    userID,
+			Name:      keyRequest.Name,
+			Prefix:    prefix,
+			KeyHash:   models.HashAPIKey(rawKey),
+			Scopes:    models.StringList(keyRequest.Scopes),
+			ExpiresAt: keyRequest.ExpiresAt,
+		}
+
+		DB := db.GetDB()
+		if result := DB.Create(&key); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "API key created. Store it now; it can't be shown again.",
+			"key":     rawKey,
+			"api_key": key,
+		})
+	}
+}
+
+// GetAPIKey retrieves a single API key's details (never the key itself)
+func GetAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := findUserAPIKey(c)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, key)
+	}
+}
+
+// RevokeAPIKey stops an API key from working. The record is kept for auditing.
+func RevokeAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := findUserAPIKey(c)
+		if !ok {
+			return
+		}
+		if key.RevokedAt != nil {
+			c.JSON(http.StatusOK, gin.H{"message": "API key was already revoked", "api_key": key})
+			return
+		}
+
+		now := time.Now()
+		DB := db.GetDB()
+		if result := DB.Model(&key).Update("revoked_at", now); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "API key revoked", "api_key": key})
+	}
+}
+
+// findUserAPIKey loads the API key from the URL, making sure it belongs to the current user
+func findUserAPIKey(c *gin.Context) (models.APIKey, bool) {
+	var key models.APIKey
+
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return key, false
+	}
+
+	DB := db.GetDB()
+	if result := DB.Where("id = ? AND user_id = ?", c.Param("key_id"), userID).First(&key); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API key: " + result.Error.Error()})
+		}
+		return key, false
+	}
+	return key, true
+}
+
+// generateAPIKey returns a new key of the form inv_<8 hex characters>_<secret> and its prefix
+func generateAPIKey() (string, string, error) {
+	id := make([]byte, 4)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	prefix := models.APIKeyPrefix + hex.EncodeToString(id)
+	return prefix + "_" + base64.RawURLEncoding.EncodeToString(secret), prefix, nil
+}
@@ -0,0 +1,427 @@
+// routes/assets.go
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errAssetUnavailable is returned when an asset is not in the state an operation needs
+var errAssetUnavailable = fmt.Errorf("asset unavailable")
+
+// errItemCheckedOut is returned when deleting an item whose assets are out on loan
+var errItemCheckedOut = fmt.Errorf("item has assets checked out")
+
+// AssetRoutes sets up the routes for serial-numbered assets and their custody
+func AssetRoutes(router *gin.Engine) {
+	itemAssetRoutes := router.Group("/items")
+	itemAssetRoutes.Use(middleware.AuthMiddleware())
+	{
+		itemAssetRoutes.GET("/:item_id/assets", GetItemAssets())
+		itemAssetRoutes.POST("/:item_id/assets", CreateAsset())
+	}
+
+	assetRoutes := router.Group("/assets")
+	assetRoutes.Use(middleware.AuthMiddleware())
+	{
+		assetRoutes.GET("/", GetAssets())
+		assetRoutes.GET("/overdue", GetOverdueAssets())
+		assetRoutes.GET("/:asset_id", GetAsset())
+		assetRoutes.PUT("/:asset_id", UpdateAsset())
+		assetRoutes.DELETE("/:asset_id", DeleteAsset())
+		assetRoutes.POST("/:asset_id/checkout", CheckOutAsset())
+		assetRoutes.POST("/:asset_id/checkin", CheckInAsset())
+		assetRoutes.GET("/:asset_id/history", GetAssetHistory())
+	}
+}
+
+// GetItemAssets lists the serial-numbered units of an item
+func GetItemAssets() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		var assets []models.Asset
+		DB := db.GetDB()
+		if result := DB.Where("item_id = ?", item.ID).Order("serial_number").Find(&assets); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve assets: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"assets": assets})
+	}
+}
+
+// CreateAsset registers a serial-numbered unit under an item
+func CreateAsset() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var assetData struct {
+			SerialNumber string `json:"serial_number" binding:"required"`
+			Notes        string `json:"notes"`
+		}
+		if err := c.ShouldBindJSON(&assetData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		// Serial numbers are unique per owner
+		DB := db.GetDB()
+		var existing models.Asset
+		if result := DB.Where("user_id = ? AND serial_number = ?", item.UserID, assetData.SerialNumber).First(&existing); result.Error == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "An asset with this serial number already exists"})
+			return
+		}
+
+		asset := models.Asset{
+			ItemID:       item.ID,
+			UserID:       item.UserID,
+			SerialNumber: assetData.SerialNumber,
+			Status:       models.AssetAvailable,
+			Notes:        assetData.Notes,
+		}
+		if result := DB.Create(&asset); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create asset: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"asset": asset})
+	}
+}
+
+// GetAssets lists all of the authenticated user's assets, optionally filtered by status
+func GetAssets() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		DB := db.GetDB()
+		query := DB.Where("user_id = ?", userID)
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+
+		var assets []models.Asset
+		if result := query.Order("serial_number").Find(&assets); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve assets: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"assets": assets})
+	}
+}
+
+// GetAsset retrieves an asset and, if it's checked out, who has it
+func GetAsset() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		asset, ok := findUserAsset(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		var current models.Checkout
+		result := DB.Preload("Borrower").Where("asset_id = ? AND returned_at IS NULL", asset.ID).First(&current)
+		if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve checkout: " + result.Error.Error()})
+			return
+		}
+
+		response := gin.H{"asset": asset}
+		if result.Error == nil {
+			response["current_checkout"] = current
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// UpdateAsset changes an asset's serial number, notes or retirement status
+func UpdateAsset() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var assetData struct {
+			SerialNumber string `json:"serial_number" binding:"required"`
+			Notes        string `json:"notes"`
+			Status       string `json:"status" binding:"omitempty,oneof=available retired"`
+		}
+		if err := c.ShouldBindJSON(&assetData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		asset, ok := findUserAsset(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		if assetData.SerialNumber != asset.SerialNumber {
+			var existing models.Asset
+			if result := DB.Where("user_id = ? AND serial_number = ? AND id <> ?", asset.UserID, assetData.SerialNumber, asset.ID).First(&existing); result.Error == nil {
+				c.JSON(http.StatusConflict, gin.H{"error": "An asset with this serial number already exists"})
+				return
+			}
+		}
+
+		// Status changes go through check-out/check-in while the asset is lent out
+		if assetData.Status != "" && assetData.Status != asset.Status {
+			if asset.Status == models.AssetCheckedOut {
+				c.JSON(http.StatusConflict, gin.H{"error": "Check the asset in before changing its status"})
+				return
+			}
+			asset.Status = assetData.Status
+		}
+		asset.SerialNumber = assetData.SerialNumber
+		asset.Notes = assetData.Notes
+
+		if result := DB.Save(&asset); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update asset: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"asset": asset})
+	}
+}
+
+// DeleteAsset removes an asset that was never lent out; others should be retired instead
+func DeleteAsset() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		asset, ok := findUserAsset(c)
+		if !ok {
+			return
+		}
+
+		var count int64
+		DB := db.GetDB()
+		if result := DB.Model(&models.Checkout{}).Where("asset_id = ?", asset.ID).Count(&count); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check custody history: " + result.Error.Error()})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete an asset with custody history; retire it instead"})
+			return
+		}
+
+		if result := DB.Delete(&asset); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete asset: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Asset deleted successfully"})
+	}
+}
+
+// CheckOutAsset lends an available asset to a borrower with an optional due date
+func CheckOutAsset() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var checkoutData struct {
+			BorrowerID uint       `json:"borrower_id" binding:"required"`
+			DueAt      *time.Time `json:"due_at"`
+			Notes      string     `json:"notes"`
+		}
+		if err := c.ShouldBindJSON(&checkoutData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		asset, ok := findUserAsset(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		var borrower models.Borrower
+		if result := DB.Where("id = ? AND user_id = ?", checkoutData.BorrowerID, asset.UserID).First(&borrower); result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Borrower not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve borrower: " + result.Error.Error()})
+			}
+			return
+		}
+
+		if checkoutData.DueAt != nil && !checkoutData.DueAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Due date must be in the future"})
+			return
+		}
+
+		checkout := models.Checkout{
+			AssetID:      asset.ID,
+			BorrowerID:   borrower.ID,
+			UserID:       asset.UserID,
+			CheckedOutAt: time.Now(),
+			DueAt:        checkoutData.DueAt,
+			Notes:        checkoutData.Notes,
+		}
+
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			// Lock the asset so two check-outs can't race
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&asset, asset.ID).Error; err != nil {
+				return err
+			}
+			if asset.Status != models.AssetAvailable {
+				return errAssetUnavailable
+			}
+			if err := tx.Omit(clause.Associations).Create(&checkout).Error; err != nil {
+				return err
+			}
+			asset.Status = models.AssetCheckedOut
+			return tx.Model(&asset).Update("status", asset.Status).Error
+		})
+		if err == errAssetUnavailable {
+			c.JSON(http.StatusConflict, gin.H{"error": "Asset is not available (status: " + asset.Status + ")"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check out asset: " + err.Error()})
+			return
+		}
+
+		checkout.Borrower = borrower
+		c.JSON(http.StatusCreated, gin.H{"checkout": checkout, "asset": asset})
+	}
+}
+
+// CheckInAsset returns a checked-out asset and closes its custody record
+func CheckInAsset() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var checkinData struct {
+			Notes string `json:"notes"`
+		}
+		// The body is optional
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&checkinData); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+				return
+			}
+		}
+
+		asset, ok := findUserAsset(c)
+		if !ok {
+			return
+		}
+
+		var checkout models.Checkout
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&asset, asset.ID).Error; err != nil {
+				return err
+			}
+			if asset.Status != models.AssetCheckedOut {
+				return errAssetUnavailable
+			}
+			if err := tx.Where("asset_id = ? AND returned_at IS NULL", asset.ID).First(&checkout).Error; err != nil {
+				return err
+			}
+
+			now := time.Now()
+			checkout.ReturnedAt = &now
+			checkout.ReturnNotes = checkinData.Notes
+			if err := tx.Omit(clause.Associations).Save(&checkout).Error; err != nil {
+				return err
+			}
+			asset.Status = models.AssetAvailable
+			return tx.Model(&asset).Update("status", asset.Status).Error
+		})
+		if err == errAssetUnavailable {
+			c.JSON(http.StatusConflict, gin.H{"error": "Asset is not checked out"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check in asset: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"checkout": checkout, "asset": asset})
+	}
+}
+
+// GetAssetHistory lists every custody period of an asset, newest first
+func GetAssetHistory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		asset, ok := findUserAsset(c)
+		if !ok {
+			return
+		}
+
+		var checkouts []models.Checkout
+		DB := db.GetDB()
+		if result := DB.Preload("Borrower").Where("asset_id = ?", asset.ID).Order("checked_out_at DESC").Find(&checkouts); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve custody history: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"asset": asset, "history": checkouts})
+	}
+}
+
+// GetOverdueAssets lists checked-out assets that are past their due date
+func GetOverdueAssets() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		now := time.Now()
+		var checkouts []models.Checkout
+		DB := db.GetDB()
+		if result := DB.Preload("Asset").Preload("Borrower").
+			Where("user_id = ? AND returned_at IS NULL AND due_at IS NOT NULL AND due_at < ?", userID, now).
+			Order("due_at").
+			Find(&checkouts); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve overdue assets: " + result.Error.Error()})
+			return
+		}
+
+		overdue := make([]gin.H, 0, len(checkouts))
+		for _, checkout := range checkouts {
+			overdue = append(overdue, gin.H{
+				"checkout":     checkout,
+				"days_overdue": int(now.Sub(*checkout.DueAt).Hours() / 24),
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{"overdue": overdue})
+	}
+}
+
+// findUserAsset loads the asset named in the URL if it belongs to the authenticated user.
+// It writes the error response itself and reports whether the handler should continue.
+func findUserAsset(c *gin.Context) (models.Asset, bool) {
+	var asset models.Asset
+
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return asset, false
+	}
+
+	DB := db.GetDB()
+	if result := DB.Where("id = ? AND user_id = ?", c.Param("asset_id"), userID).First(&asset); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve asset: " + result.Error.Error()})
+		}
+		return asset, false
+	}
+	return asset, true
+}
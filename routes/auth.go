@@ -10,9 +10,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/mailer"
+	"github.com/sidhant-sriv/inventory-api/middleware"
 	"github.com/sidhant-sriv/inventory-api/models"
+	"github.com/sidhant-sriv/inventory-api/passwords"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm" // Import gorm if you need to check for specific gorm errors like ErrRecordNotFound
 )
 
@@ -23,18 +25,22 @@ func AuthRoutes(router *gin.Engine) {
 		auth.POST("/register", Register())
 		auth.POST("/login", Login())
 		auth.POST("/refresh", RefreshToken())
-		auth.GET("/check-user", CheckUserExists()) // Debug endpoint
+		auth.POST("/verify-email", VerifyEmail())
+		auth.POST("/verify-email/resend", middleware.AuthMiddleware(), RequestEmailVerification())
+		auth.POST("/password/forgot", ForgotPassword())
+		auth.POST("/password/reset", ResetPassword())
 	}
 }
 
-// Register handles new user registration.
+// Register handles new user registration. The response is the same whether or not the
+// email is already registered so it can't be used to discover accounts; the user signs in
+// once registered.
 func Register() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var user models.User
 		var registerRequest struct {
 			Name     string `json:"name" binding:"required"`
 			Email    string `json:"email" binding:"required,email"`
-			Password string `json:"password" binding:"required,min=6"` // Example: minimum 6 characters
+			Password string `json:"password" binding:"required"` // checked against the password policy
 		}
 
 		// Basic validation (consider adding more robust validation)
@@ -43,20 +49,10 @@ func Register() gin.HandlerFunc {
 			return
 		}
 
-		// Map the validated request to the User model
-		user.Name = registerRequest.Name
-		user.Email = registerRequest.Email
-		user.Password = registerRequest.Password
-
-		// Hash the password
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-		if err != nil {
-			fmt.Printf("Error hashing password: %v\n", err) // Log internal error
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process registration"})
+		if err := passwords.Check(registerRequest.Password, registerRequest.Email, registerRequest.Name); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
 			return
 		}
-		// Store the hashed password, not the plain text one
-		user.Password = string(hashedPassword)
 
 		// Get DB connection
 		DB := db.GetDB()
@@ -66,39 +62,75 @@ func Register() gin.HandlerFunc {
 			return
 		}
 
-		// Create the user in database
-		// Clear the plain text password before saving (though it's already hashed)
-		// user.Password = string(hashedPassword) // Already done above
-
-		if result := DB.Create(&user); result.Error != nil {
-			// Check for duplicate email or other DB constraints
-			// Note: Specific error checking might depend on your database driver
-			fmt.Printf("Error creating user in DB: %v\n", result.Error) // Log internal error
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user. Email might already be registered."})
+		if err := registerUser(DB, registerRequest.Name, registerRequest.Email, registerRequest.Password); err != nil {
+			fmt.Printf("Error registering user: %v\n", err) // Log internal error
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process registration"})
 			return
 		}
 
-		// Generate JWT tokens
-		accessToken, refreshToken, err := generateTokens(user.ID)
-		if err != nil {
-			fmt.Printf("Error generating tokens: %v\n", err) // Log internal error
-			// Consider if user should be informed or if this requires cleanup
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to finalize registration"})
-			return
+		c.JSON(http.StatusAccepted, gin.H{"message": registrationMessage})
+	}
+}
+
+// registrationMessage is the reply to every registration, new email or not
+const registrationMessage = "Registration received. Check your email to verify your address, then sign in."
+
+// registerUser creates a user and sends the verification email. The password must already
+// have passed the password policy. If the email is already
+// registered the owner is told about the attempt instead and no error is returned, so
+// callers respond the same way either way.
+func registerUser(DB *gorm.DB, name string, email string, password string) error {
+	// Hash first so both paths take about as long
+	hashedPassword, err := passwords.Hash(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	var existing models.User
+	result := DB.Where("LOWER(email) = LOWER(?)", email).First(&existing)
+	if result.Error == nil {
+		sendExistingAccountNotice(existing)
+		return nil
+	}
+	if result.Error != gorm.ErrRecordNotFound {
+		return result.Error
+	}
+
+	// Roles are granted by administrators, never self-assigned
+	user := models.User{
+		Name:     name,
+		Email:    email,
+		Password: hashedPassword,
+		Role:     models.RoleUser,
+	}
+	if result := DB.Create(&user); result.Error != nil {
+		// Lost a race with another registration, or the address belongs to a deleted account
+		if DB.Unscoped().Where("email = ?", email).First(&existing).Error == nil {
+			if !existing.DeletedAt.Valid {
+				sendExistingAccountNotice(existing)
+			}
+			return nil
 		}
+		return result.Error
+	}
+	fmt.Printf("Registered user ID %d\n", user.ID)
 
-		// Return newly created user info (excluding password) and tokens
-		c.JSON(http.StatusCreated, gin.H{
-			"message": "User registered successfully",
-			"user": gin.H{
-				"id":    user.ID,
-				"name":  user.Name,
-				"email": user.Email,
-			},
-			"access_token":  accessToken,
-			"refresh_token": refreshToken,
-		})
+	// Ask the user to confirm the address; registration succeeds even if sending fails
+	if err := sendVerificationEmail(DB, user); err != nil {
+		fmt.Printf("Error issuing verification token for user ID %d: %v\n", user.ID, err)
 	}
+	return nil
+}
+
+// sendExistingAccountNotice tells the owner of an address that someone tried to register with it
+func sendExistingAccountNotice(user models.User) {
+	sendEmail(mailer.Message{
+		To:      user.Email,
+		Subject: "You already have an account",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone tried to register a new inventory account with this email address, "+
+			"but you already have one. If it was you, sign in instead, or reset your password if you've forgotten it. "+
+			"If it wasn't, you can ignore this email.\n", user.Name),
+	})
 }
 
 // Login handles user login requests.
@@ -123,57 +155,125 @@ func Login() gin.HandlerFunc {
 			return
 		}
 
+		// Locked-out emails and IPs are turned away before any password is checked, and the
+		// attempt is counted up front
+		attempt := beginLoginAttempt(c, loginRequest.Email)
+		if attempt == nil {
+			return
+		}
+
 		// Find user by email
 		var user models.User
-		fmt.Printf("Attempting login with email: %s\n", loginRequest.Email) // Debug log
 
 		// Use First to get a single record. It returns gorm.ErrRecordNotFound if no user is found.
 		result := DB.Where("email = ?", loginRequest.Email).First(&user)
 
 		// Check if user was found
 		if result.Error != nil {
-			fmt.Printf("Database error during login lookup for email %s: %v\n", loginRequest.Email, result.Error) // Log internal error
 			if result.Error == gorm.ErrRecordNotFound {
+				// Take as long as a wrong password and count the failure the same way
+				compareDummyPassword(loginRequest.Password)
+				attempt.fail(c, nil)
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"}) // User not found
 			} else {
+				attempt.refund()
+				fmt.Printf("Database error during login lookup: %v\n", result.Error)
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error during login"}) // Other DB error
 			}
 			return
 		}
 
-		fmt.Printf("Found user with ID: %d. Comparing password.\n", user.ID) // Debug log
-		// fmt.Printf("Stored Hash: %s\n", user.Password) // Optional: Debug log for hash (be careful in prod)
-		// fmt.Printf("Password Attempt: %s\n", loginRequest.Password) // Don't log plaintext passwords in production
-
-		// Verify password using bcrypt
-		err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginRequest.Password))
-		if err != nil {
+		// Verify password against the stored hash, whichever algorithm made it
+		match, needsRehash, err := passwords.Verify(loginRequest.Password, user.Password)
+		if !match {
 			// Password does not match
-			fmt.Printf("Password comparison failed for user ID %d: %v\n", user.ID, err) // Log internal error (usually bcrypt.ErrMismatchedHashAndPassword)
+			if err != nil {
+				fmt.Printf("Password comparison failed for user ID %d: %v\n", user.ID, err) // Log internal error
+			}
+			attempt.fail(c, &user)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 			return
 		}
+		attempt.refund()
 
-		// Password is correct, generate tokens
-		accessToken, refreshToken, err := generateTokens(user.ID)
+		// Upgrade the stored hash now that we have the password, e.g. from bcrypt to argon2id
+		if needsRehash {
+			rehashPassword(DB, user, loginRequest.Password)
+		}
+
+		// Password is correct, generate tokens (or ask for the second factor)
+		respondWithLogin(c, user)
+	}
+}
+
+// rehashPassword stores a fresh hash of a correct password made with the current hasher.
+// Failing to do so doesn't stop the login.
+func rehashPassword(DB *gorm.DB, user models.User, password string) {
+	hashedPassword, err := passwords.Hash(password)
+	if err == nil {
+		// Only replace the hash that was just checked, in case the password changed meanwhile
+		err = DB.Model(&models.User{}).Where("id = ? AND password = ?", user.ID, user.Password).Update("password", hashedPassword).Error
+	}
+	if err != nil {
+		fmt.Printf("Error rehashing password for user ID %d: %v\n", user.ID, err)
+		return
+	}
+	fmt.Printf("Rehashed password for user ID %d\n", user.ID)
+}
+
+// respondWithLogin finishes a successful first login step. With two-factor authentication
+// on it returns a short-lived MFA challenge token for /auth/login/mfa instead of the tokens.
+func respondWithLogin(c *gin.Context, user models.User) {
+	if user.MFAEnabledAt != nil {
+		mfaToken, err := generateMFAToken(user.ID)
 		if err != nil {
-			fmt.Printf("Error generating tokens for user ID %d: %v\n", user.ID, err) // Log internal error
+			fmt.Printf("Error generating MFA token for user ID %d: %v\n", user.ID, err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate login tokens"})
 			return
 		}
-
-		// Return user info (excluding password) and tokens
 		c.JSON(http.StatusOK, gin.H{
-			"message": "Login successful",
-			"user": gin.H{
-				"id":    user.ID,
-				"name":  user.Name,
-				"email": user.Email,
-			},
-			"access_token":  accessToken,
-			"refresh_token": refreshToken,
+			"message":      "Two-factor authentication required",
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
 		})
+		return
 	}
+	respondWithTokens(c, user)
+}
+
+// respondWithTokens issues the access/refresh pair for a fully authenticated user
+func respondWithTokens(c *gin.Context, user models.User) {
+	// A completed sign-in forgets the account's earlier failed attempts
+	if err := clearLoginFailures(db.GetDB(), user.Email); err != nil {
+		fmt.Printf("Error clearing failed logins for user ID %d: %v\n", user.ID, err)
+	}
+
+	session, err := startSession(c, user)
+	if err != nil {
+		fmt.Printf("Error starting session for user ID %d: %v\n", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate login tokens"})
+		return
+	}
+
+	accessToken, refreshToken, err := generateTokens(user.ID, session)
+	if err != nil {
+		fmt.Printf("Error generating tokens for user ID %d: %v\n", user.ID, err) // Log internal error
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate login tokens"})
+		return
+	}
+
+	// Return user info (excluding password) and tokens
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successful",
+		"user": gin.H{
+			"id":             user.ID,
+			"name":           user.Name,
+			"email":          user.Email,
+			"email_verified": user.EmailVerifiedAt != nil,
+		},
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	})
 }
 
 // RefreshToken handles requests to refresh JWT access tokens using a valid refresh token.
@@ -250,8 +350,22 @@ func RefreshToken() gin.HandlerFunc {
 			return
 		}
 
+		// Rotate the session's refresh token; an old one being replayed ends the session
+		sessionID, _ := claims["sid"].(float64)
+		tokenID, _ := claims["jti"].(string)
+		session, err := rotateSession(c, uint(sessionID), userID, tokenID)
+		if err == errSessionEnded {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has ended, please log in again"})
+			return
+		}
+		if err != nil {
+			fmt.Printf("Error refreshing session for user ID %d: %v\n", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate new tokens"})
+			return
+		}
+
 		// Generate new access and refresh tokens
-		newAccessToken, newRefreshToken, err := generateTokens(userID)
+		newAccessToken, newRefreshToken, err := generateTokens(userID, session)
 		if err != nil {
 			fmt.Printf("Error generating tokens during refresh for user ID %d: %v\n", userID, err) // Log internal error
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate new tokens"})
@@ -267,47 +381,9 @@ func RefreshToken() gin.HandlerFunc {
 	}
 }
 
-// CheckUserExists is a debug endpoint to verify if a user exists by email.
-func CheckUserExists() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		email := c.Query("email")
-		if email == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Email query parameter is required"})
-			return
-		}
-
-		DB := db.GetDB()
-		if DB == nil {
-			fmt.Println("Error: Database connection is nil")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection error"})
-			return
-		}
-
-		var user models.User
-		result := DB.Where("email = ?", email).Select("id", "name", "email").First(&user) // Select only needed fields
-
-		if result.Error != nil {
-			if result.Error == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusOK, gin.H{"exists": false, "message": "User not found"})
-			} else {
-				fmt.Printf("Database error checking user existence for email %s: %v\n", email, result.Error)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error", "details": result.Error.Error()})
-			}
-			return
-		}
-
-		// User exists, return selected details (no password)
-		c.JSON(http.StatusOK, gin.H{
-			"exists":  true,
-			"user_id": user.ID,
-			"name":    user.Name,
-			"email":   user.Email,
-		})
-	}
-}
-
 // generateTokens is a helper function to create new JWT access and refresh tokens.
-func generateTokens(userID uint) (string, string, error) {
+// Both carry the session ID; the refresh token also carries the session's current refresh token ID.
+func generateTokens(userID uint, session models.Session) (string, string, error) {
 	jwtSecret := os.Getenv("JWT_SECRET_KEY")
 	if jwtSecret == "" {
 		fmt.Println("CRITICAL: JWT_SECRET_KEY environment variable not set.")
@@ -318,6 +394,7 @@ func generateTokens(userID uint) (string, string, error) {
 	// Create access token (shorter lifespan)
 	accessTokenClaims := jwt.MapClaims{
 		"user_id": userID,
+		"sid":     session.ID,
 		"exp":     time.Now().Add(time.Hour * 1).Unix(), // Expires in 1 hour
 		"iat":     time.Now().Unix(),                    // Issued at
 		"type":    "access",                             // Token type identifier
@@ -332,9 +409,11 @@ func generateTokens(userID uint) (string, string, error) {
 	// Create refresh token (longer lifespan)
 	refreshTokenClaims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24 * 7).Unix(), // Expires in 7 days
-		"iat":     time.Now().Unix(),                         // Issued at
-		"type":    "refresh",                                 // Token type identifier
+		"sid":     session.ID,
+		"jti":     session.RefreshTokenID,
+		"exp":     session.ExpiresAt.Unix(), // Expires in 7 days
+		"iat":     time.Now().Unix(),        // Issued at
+		"type":    "refresh",                // Token type identifier
 	}
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshTokenClaims)
 	refreshTokenString, err := refreshToken.SignedString(secretKeyBytes)
@@ -0,0 +1,186 @@
+// routes/borrowers.go
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// BorrowerRoutes sets up the routes for managing borrower contacts
+func BorrowerRoutes(router *gin.Engine) {
+	borrowerRoutes := router.Group("/borrowers")
+	borrowerRoutes.Use(middleware.AuthMiddleware())
+	{
+		borrowerRoutes.POST("/", CreateBorrower())
+		borrowerRoutes.GET("/", GetBorrowers())
+		borrowerRoutes.GET("/:borrower_id", GetBorrower())
+		borrowerRoutes.PUT("/:borrower_id", UpdateBorrower())
+		borrowerRoutes.DELETE("/:borrower_id", DeleteBorrower())
+	}
+}
+
+// borrowerRequest is the payload for creating or updating a borrower
+type borrowerRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Email string `json:"email" binding:"omitempty,email"`
+	Phone string `json:"phone"`
+	Notes string `json:"notes"`
+}
+
+// CreateBorrower adds a contact that assets can be checked out to
+func CreateBorrower() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var borrowerData borrowerRequest
+		if err := c.ShouldBindJSON(&borrowerData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		borrower := models.Borrower{
+			UserID: userID,
+			Name:   borrowerData.Name,
+			Email:  borrowerData.Email,
+			Phone:  borrowerData.Phone,
+			Notes:  borrowerData.Notes,
+		}
+
+		DB := db.GetDB()
+		if result := DB.Create(&borrower); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create borrower: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"borrower": borrower})
+	}
+}
+
+// GetBorrowers lists the authenticated user's borrowers
+func GetBorrowers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		var borrowers []models.Borrower
+		DB := db.GetDB()
+		if result := DB.Where("user_id = ?", userID).Order("name").Find(&borrowers); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve borrowers: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"borrowers": borrowers})
+	}
+}
+
+// GetBorrower retrieves a borrower along with the assets they currently hold
+func GetBorrower() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		borrower, ok := findUserBorrower(c)
+		if !ok {
+			return
+		}
+
+		var checkouts []models.Checkout
+		DB := db.GetDB()
+		if result := DB.Preload("Asset").Where("borrower_id = ? AND returned_at IS NULL", borrower.ID).Find(&checkouts); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve checkouts: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"borrower": borrower, "checked_out": checkouts})
+	}
+}
+
+// UpdateBorrower updates a borrower's contact details
+func UpdateBorrower() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var borrowerData borrowerRequest
+		if err := c.ShouldBindJSON(&borrowerData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		borrower, ok := findUserBorrower(c)
+		if !ok {
+			return
+		}
+
+		borrower.Name = borrowerData.Name
+		borrower.Email = borrowerData.Email
+		borrower.Phone = borrowerData.Phone
+		borrower.Notes = borrowerData.Notes
+
+		DB := db.GetDB()
+		if result := DB.Save(&borrower); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update borrower: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"borrower": borrower})
+	}
+}
+
+// DeleteBorrower removes a borrower who has no history of checkouts
+func DeleteBorrower() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		borrower, ok := findUserBorrower(c)
+		if !ok {
+			return
+		}
+
+		// Keep custody history intact: borrowers that ever held an asset can't be removed
+		var count int64
+		DB := db.GetDB()
+		if result := DB.Model(&models.Checkout{}).Where("borrower_id = ?", borrower.ID).Count(&count); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check checkouts: " + result.Error.Error()})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete a borrower with checkout history"})
+			return
+		}
+
+		if result := DB.Delete(&borrower); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete borrower: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Borrower deleted successfully"})
+	}
+}
+
+// findUserBorrower loads the borrower named in the URL if it belongs to the authenticated user.
+// It writes the error response itself and reports whether the handler should continue.
+func findUserBorrower(c *gin.Context) (models.Borrower, bool) {
+	var borrower models.Borrower
+
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return borrower, false
+	}
+
+	DB := db.GetDB()
+	if result := DB.Where("id = ? AND user_id = ?", c.Param("borrower_id"), userID).First(&borrower); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Borrower not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve borrower: " + result.Error.Error()})
+		}
+		return borrower, false
+	}
+	return borrower, true
+}
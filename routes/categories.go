@@ -0,0 +1,254 @@
+// routes/categories.go
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// CategoryRoutes sets up the routes for the item category hierarchy
+func CategoryRoutes(router *gin.Engine) {
+	categoryRoutes := router.Group("/categories")
+	categoryRoutes.Use(middleware.AuthMiddleware())
+	{
+		categoryRoutes.POST("/", CreateCategory())
+		categoryRoutes.GET("/", GetCategories())
+		categoryRoutes.GET("/:category_id", GetCategory())
+		categoryRoutes.PUT("/:category_id", UpdateCategory())
+		categoryRoutes.DELETE("/:category_id", DeleteCategory())
+	}
+}
+
+// categoryRequest is the payload for creating or updating a category
+type categoryRequest struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// CreateCategory adds a category, optionally under a parent category
+func CreateCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var categoryData categoryRequest
+		if err := c.ShouldBindJSON(&categoryData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		DB := db.GetDB()
+		if !userOwnsCategory(DB, userID, categoryData.ParentID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent category not found"})
+			return
+		}
+
+		category := models.Category{
+			UserID:   userID,
+			Name:     categoryData.Name,
+			ParentID: categoryData.ParentID,
+		}
+		if result := DB.Create(&category); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"category": category})
+	}
+}
+
+// GetCategories lists the user's categories, flat by default or nested with tree=true
+func GetCategories() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		var categories []models.Category
+		DB := db.GetDB()
+		if result := DB.Where("user_id = ?", userID).Order("name").Find(&categories); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve categories: " + result.Error.Error()})
+			return
+		}
+
+		if c.Query("tree") == "true" {
+			c.JSON(http.StatusOK, gin.H{"categories": buildCategoryTree(categories, nil)})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"categories": categories})
+	}
+}
+
+// GetCategory retrieves a category with its direct children
+func GetCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		category, ok := findUserCategory(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		if result := DB.Where("parent_id = ?", category.ID).Order("name").Find(&category.Children); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve subcategories: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"category": category})
+	}
+}
+
+// UpdateCategory renames a category or moves it under a different parent
+func UpdateCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var categoryData categoryRequest
+		if err := c.ShouldBindJSON(&categoryData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		category, ok := findUserCategory(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		if !userOwnsCategory(DB, category.UserID, categoryData.ParentID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent category not found"})
+			return
+		}
+
+		// A category can't be moved under itself or one of its descendants
+		if categoryData.ParentID != nil {
+			subtree, err := categorySubtreeIDs(DB, category.UserID, category.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category hierarchy: " + err.Error()})
+				return
+			}
+			for _, id := range subtree {
+				if id == *categoryData.ParentID {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "A category cannot be moved under itself or its subcategories"})
+					return
+				}
+			}
+		}
+
+		category.Name = categoryData.Name
+		category.ParentID = categoryData.ParentID
+		if result := DB.Save(&category); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"category": category})
+	}
+}
+
+// DeleteCategory removes a leaf category with its custom fields; its items become uncategorised
+// and lose their custom values
+func DeleteCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		category, ok := findUserCategory(c)
+		if !ok {
+			return
+		}
+
+		var count int64
+		DB := db.GetDB()
+		if result := DB.Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&count); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check subcategories: " + result.Error.Error()})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete category with subcategories"})
+			return
+		}
+
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Item{}).Where("category_id = ?", category.ID).
+				Updates(map[string]interface{}{"category_id": nil, "custom_fields": nil}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("category_id = ?", category.ID).Delete(&models.CustomField{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&category).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+	}
+}
+
+// buildCategoryTree nests a flat category list under the given parent
+func buildCategoryTree(categories []models.Category, parentID *uint) []models.Category {
+	tree := []models.Category{}
+	for _, category := range categories {
+		if (parentID == nil && category.ParentID == nil) || (parentID != nil && category.ParentID != nil && *category.ParentID == *parentID) {
+			id := category.ID
+			category.Children = buildCategoryTree(categories, &id)
+			tree = append(tree, category)
+		}
+	}
+	return tree
+}
+
+// categorySubtreeIDs returns a category's ID together with all of its descendants
+func categorySubtreeIDs(DB *gorm.DB, userID uint, categoryID uint) ([]uint, error) {
+	var ids []uint
+	result := DB.Raw(`WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = ? AND user_id = ?
+			UNION
+			SELECT categories.id FROM categories JOIN subtree ON categories.parent_id = subtree.id
+		)
+		SELECT id FROM subtree`, categoryID, userID).Scan(&ids)
+	return ids, result.Error
+}
+
+// userOwnsCategory reports whether an optional category reference points at one of the user's categories
+func userOwnsCategory(DB *gorm.DB, userID uint, categoryID *uint) bool {
+	if categoryID == nil {
+		return true
+	}
+	var count int64
+	if result := DB.Model(&models.Category{}).Where("id = ? AND user_id = ?", *categoryID, userID).Count(&count); result.Error != nil {
+		return false
+	}
+	return count > 0
+}
+
+// findUserCategory loads the category named in the URL if it belongs to the authenticated user.
+// It writes the error response itself and reports whether the handler should continue.
+func findUserCategory(c *gin.Context) (models.Category, bool) {
+	var category models.Category
+
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return category, false
+	}
+
+	DB := db.GetDB()
+	if result := DB.Where("id = ? AND user_id = ?", c.Param("category_id"), userID).First(&category); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve category: " + result.Error.Error()})
+		}
+		return category, false
+	}
+	return category, true
+}
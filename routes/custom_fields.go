@@ -0,0 +1,406 @@
+// routes/custom_fields.go
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// customFieldKeyPattern keeps keys safe to use in URLs and JSON paths
+var customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// CustomFieldRoutes sets up the routes for per-category custom field definitions
+func CustomFieldRoutes(router *gin.Engine) {
+	categoryFieldRoutes := router.Group("/categories")
+	categoryFieldRoutes.Use(middleware.AuthMiddleware())
+	{
+		categoryFieldRoutes.GET("/:category_id/fields", GetCategoryFields())
+		categoryFieldRoutes.POST("/:category_id/fields", CreateCustomField())
+	}
+
+	fieldRoutes := router.Group("/fields")
+	fieldRoutes.Use(middleware.AuthMiddleware())
+	{
+		fieldRoutes.GET("/", GetCustomFields())
+		fieldRoutes.PUT("/:field_id", UpdateCustomField())
+		fieldRoutes.DELETE("/:field_id", DeleteCustomField())
+	}
+}
+
+// customFieldRequest is the payload for creating or updating a custom field
+type customFieldRequest struct {
+	Key      string   `json:"key" binding:"required"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type" binding:"required,oneof=text number date enum boolean url"`
+	Options  []string `json:"options"`
+	Required bool     `json:"required"`
+}
+
+// validate checks the definition itself (not item values)
+func (request customFieldRequest) validate() error {
+	if !customFieldKeyPattern.MatchString(request.Key) {
+		return fmt.Errorf("key must start with a letter and contain only lowercase letters, digits and underscores")
+	}
+	if request.Type == models.FieldEnum && len(request.Options) == 0 {
+		return fmt.Errorf("enum fields need at least one option")
+	}
+	if request.Type != models.FieldEnum && len(request.Options) > 0 {
+		return fmt.Errorf("options are only allowed for enum fields")
+	}
+	return nil
+}
+
+// GetCategoryFields lists the fields that apply to a category, including inherited ones
+func GetCategoryFields() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		category, ok := findUserCategory(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		fields, err := customFieldsForCategory(DB, category.UserID, &category.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve fields: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"fields": fields})
+	}
+}
+
+// GetCustomFields lists every custom field the user has defined
+func GetCustomFields() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		var fields []models.CustomField
+		DB := db.GetDB()
+		if result := DB.Where("user_id = ?", userID).Order("key").Find(&fields); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve fields: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"fields": fields})
+	}
+}
+
+// CreateCustomField attaches a new field definition to a category
+func CreateCustomField() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var fieldData customFieldRequest
+		if err := c.ShouldBindJSON(&fieldData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+		if err := fieldData.validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		category, ok := findUserCategory(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		var existing models.CustomField
+		if result := DB.Where("user_id = ? AND key = ?", category.UserID, fieldData.Key).First(&existing); result.Error == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "A field with this key already exists"})
+			return
+		}
+
+		field := models.CustomField{
+			UserID:     category.UserID,
+			CategoryID: category.ID,
+			Key:        fieldData.Key,
+			Label:      fieldData.Label,
+			Type:       fieldData.Type,
+			Options:    fieldData.Options,
+			Required:   fieldData.Required,
+		}
+		if field.Label == "" {
+			field.Label = field.Key
+		}
+		if result := DB.Create(&field); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create field: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"field": field})
+	}
+}
+
+// UpdateCustomField changes a field's label, options or required flag.
+// The key and type are fixed once created since existing item values depend on them.
+func UpdateCustomField() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var fieldData customFieldRequest
+		if err := c.ShouldBindJSON(&fieldData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+		if err := fieldData.validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		field, ok := findUserCustomField(c)
+		if !ok {
+			return
+		}
+		if fieldData.Key != field.Key || fieldData.Type != field.Type {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A field's key and type cannot be changed"})
+			return
+		}
+
+		field.Label = fieldData.Label
+		if field.Label == "" {
+			field.Label = field.Key
+		}
+		field.Options = fieldData.Options
+		field.Required = fieldData.Required
+
+		DB := db.GetDB()
+		if result := DB.Save(&field); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update field: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"field": field})
+	}
+}
+
+// DeleteCustomField removes a field definition and strips its values from the user's items
+func DeleteCustomField() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		field, ok := findUserCustomField(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("UPDATE items SET custom_fields = custom_fields - ? WHERE user_id = ? AND custom_fields -> ? IS NOT NULL",
+				field.Key, field.UserID, field.Key).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&field).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete field: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Field deleted successfully"})
+	}
+}
+
+// categoryAncestorIDs returns a category's ID together with all of its ancestors
+func categoryAncestorIDs(DB *gorm.DB, userID uint, categoryID uint) ([]uint, error) {
+	var ids []uint
+	result := DB.Raw(`WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM categories WHERE id = ? AND user_id = ?
+			UNION
+			SELECT categories.id, categories.parent_id FROM categories JOIN ancestors ON categories.id = ancestors.parent_id
+		)
+		SELECT id FROM ancestors`, categoryID, userID).Scan(&ids)
+	return ids, result.Error
+}
+
+// customFieldsForCategory loads the fields defined on a category and inherited from its ancestors
+func customFieldsForCategory(DB *gorm.DB, userID uint, categoryID *uint) ([]models.CustomField, error) {
+	fields := []models.CustomField{}
+	if categoryID == nil {
+		return fields, nil
+	}
+	ids, err := categoryAncestorIDs(DB, userID, *categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return fields, nil
+	}
+	result := DB.Where("user_id = ? AND category_id IN ?", userID, ids).Order("key").Find(&fields)
+	return fields, result.Error
+}
+
+// dropStaleCustomFields removes values for fields the item's new category doesn't define
+// when an update moves it out of its previous category
+func dropStaleCustomFields(DB *gorm.DB, item *models.Item, previousCategoryID *uint) error {
+	if sameCategory(item.CategoryID, previousCategoryID) || len(item.CustomFields) == 0 {
+		return nil
+	}
+	fields, err := customFieldsForCategory(DB, item.UserID, item.CategoryID)
+	if err != nil {
+		return err
+	}
+	defined := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		defined[field.Key] = true
+	}
+	for key := range item.CustomFields {
+		if !defined[key] {
+			delete(item.CustomFields, key)
+		}
+	}
+	return nil
+}
+
+// sameCategory compares two optional category IDs
+func sameCategory(a *uint, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// validateItemCustomFields checks an item's custom values against its category's schema
+// and normalises them (numbers as float64, dates as YYYY-MM-DD). Null values remove a key.
+func validateItemCustomFields(DB *gorm.DB, item *models.Item) error {
+	fields, err := customFieldsForCategory(DB, item.UserID, item.CategoryID)
+	if err != nil {
+		return err
+	}
+
+	definitions := make(map[string]models.CustomField, len(fields))
+	for _, field := range fields {
+		definitions[field.Key] = field
+	}
+
+	values := models.JSONMap{}
+	for key, value := range item.CustomFields {
+		if value == nil {
+			continue
+		}
+		field, ok := definitions[key]
+		if !ok {
+			return fmt.Errorf("field %q is not defined for this item's category", key)
+		}
+		normalized, err := normalizeCustomValue(field, value)
+		if err != nil {
+			return fmt.Errorf("field %q: %v", key, err)
+		}
+		values[key] = normalized
+	}
+
+	for _, field := range fields {
+		if _, ok := values[field.Key]; field.Required && !ok {
+			return fmt.Errorf("field %q is required", field.Key)
+		}
+	}
+
+	if len(values) == 0 {
+		item.CustomFields = nil
+	} else {
+		item.CustomFields = values
+	}
+	return nil
+}
+
+// normalizeCustomValue validates a single value against its field type
+func normalizeCustomValue(field models.CustomField, value interface{}) (interface{}, error) {
+	switch field.Type {
+	case models.FieldText:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		return text, nil
+	case models.FieldNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			number, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("must be a number")
+			}
+			return number, nil
+		}
+		return nil, fmt.Errorf("must be a number")
+	case models.FieldDate:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a date (YYYY-MM-DD)")
+		}
+		date, err := time.Parse("2006-01-02", text)
+		if err != nil {
+			if date, err = time.Parse(time.RFC3339, text); err != nil {
+				return nil, fmt.Errorf("must be a date (YYYY-MM-DD)")
+			}
+		}
+		return date.Format("2006-01-02"), nil
+	case models.FieldEnum:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be one of %v", []string(field.Options))
+		}
+		for _, option := range field.Options {
+			if option == text {
+				return text, nil
+			}
+		}
+		return nil, fmt.Errorf("must be one of %v", []string(field.Options))
+	case models.FieldBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			boolean, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("must be true or false")
+			}
+			return boolean, nil
+		}
+		return nil, fmt.Errorf("must be true or false")
+	case models.FieldURL:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a URL")
+		}
+		parsed, err := url.ParseRequestURI(text)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("must be an http or https URL")
+		}
+		return text, nil
+	}
+	return nil, fmt.Errorf("unknown field type %q", field.Type)
+}
+
+// findUserCustomField loads the field named in the URL if it belongs to the authenticated user.
+// It writes the error response itself and reports whether the handler should continue.
+func findUserCustomField(c *gin.Context) (models.CustomField, bool) {
+	var field models.CustomField
+
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return field, false
+	}
+
+	DB := db.GetDB()
+	if result := DB.Where("id = ? AND user_id = ?", c.Param("field_id"), userID).First(&field); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Field not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve field: " + result.Error.Error()})
+		}
+		return field, false
+	}
+	return field, true
+}
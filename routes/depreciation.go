@@ -0,0 +1,85 @@
+// routes/depreciation.go
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+)
+
+// DepreciationRoutes sets up the routes for item depreciation schedules
+func DepreciationRoutes(router *gin.Engine) {
+	depreciationRoutes := router.Group("/items")
+	depreciationRoutes.Use(middleware.AuthMiddleware())
+	{
+		depreciationRoutes.GET("/:item_id/depreciation", GetDepreciationSchedule())
+	}
+}
+
+// GetDepreciationSchedule returns an item's per-unit value at the end of each year of its useful life
+func GetDepreciationSchedule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		start := item.CreatedAt
+		if item.PurchaseDate != nil {
+			start = *item.PurchaseDate
+		}
+
+		type scheduleEntry struct {
+			Date      time.Time `json:"date"`
+			UnitValue float64   `json:"unit_value"`
+			Value     float64   `json:"value"`
+		}
+		schedule := []scheduleEntry{{Date: start, UnitValue: item.PurchasePrice, Value: item.PurchasePrice * float64(item.Quantity)}}
+		for months := 12; months < item.UsefulLifeMonths+12; months += 12 {
+			if months > item.UsefulLifeMonths {
+				months = item.UsefulLifeMonths
+			}
+			date := start.AddDate(0, months, 0)
+			value := item.ValueOn(date)
+			schedule = append(schedule, scheduleEntry{Date: date, UnitValue: value, Value: value * float64(item.Quantity)})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"item_id":            item.ID,
+			"method":             item.DepreciationMethod,
+			"useful_life_months": item.UsefulLifeMonths,
+			"currency":           itemCurrency(item),
+			"current_value":      item.CurrentValue,
+			"schedule":           schedule,
+		})
+	}
+}
+
+// validateDepreciation checks an item's depreciation settings, defaulting the method to none
+func validateDepreciation(item *models.Item) error {
+	switch item.DepreciationMethod {
+	case "":
+		item.DepreciationMethod = models.DepreciationNone
+	case models.DepreciationNone, models.DepreciationStraightLine, models.DepreciationDecliningBalance:
+	default:
+		return fmt.Errorf("method must be none, straight_line or declining_balance")
+	}
+
+	if item.DepreciationMethod == models.DepreciationNone {
+		return nil
+	}
+	if item.UsefulLifeMonths <= 0 {
+		return fmt.Errorf("useful_life_months is required")
+	}
+	if item.SalvageValue < 0 || item.SalvageValue > item.PurchasePrice {
+		return fmt.Errorf("salvage_value must be between 0 and the purchase price")
+	}
+	if item.DepreciationRate < 0 || item.DepreciationRate > models.MaxDecliningRate {
+		return fmt.Errorf("depreciation_rate must be between 0 and %g", models.MaxDecliningRate)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+// routes/events.go
+package routes
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/events"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+)
+
+// heartbeatInterval keeps idle streams alive through proxies
+const heartbeatInterval = 30 * time.Second
+
+var upgrader = websocket.Upgrader{
+	// Requests are authenticated with the bearer token, so cross-origin dashboards are allowed
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// EventRoutes sets up the real-time change stream routes
+func EventRoutes(router *gin.Engine) {
+	eventRoutes := router.Group("/events")
+	eventRoutes.Use(middleware.AuthMiddleware())
+	{
+		eventRoutes.GET("/", StreamEvents())
+		eventRoutes.GET("/ws", StreamEventsWebSocket())
+	}
+}
+
+// StreamEvents streams item and location changes as Server-Sent Events.
+// Clients resume from the Last-Event-ID header (or last_event_id query parameter). A "resync"
+// event ends the stream when the client's position is no longer in the log or it fell too
+// far behind; it should then reload its data and reconnect without a Last-Event-ID.
+func StreamEvents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		lastEventID := c.GetHeader("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = c.Query("last_event_id")
+		}
+		lastID, err := parseLastEventID(lastEventID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+
+		// Subscribe before replaying so nothing published in between is missed
+		subscription := events.Subscribe(userID)
+		defer events.Unsubscribe(subscription)
+
+		// Replay the persisted log only when the client is resuming
+		var backlog []models.Event
+		if lastEventID != "" {
+			backlog, err = events.Since(db.GetDB(), userID, lastID)
+			if err == events.ErrResyncRequired {
+				c.SSEvent("resync", gin.H{"reason": err.Error()})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load event log: " + err.Error()})
+				return
+			}
+		}
+
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no") // disable nginx response buffering
+
+		for _, event := range backlog {
+			c.Render(-1, sse.Event{Id: strconv.FormatUint(uint64(event.ID), 10), Event: event.EntityType, Data: events.ToMessage(event)})
+			lastID = event.ID
+		}
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case <-heartbeat.C:
+				c.SSEvent("ping", gin.H{"time": time.Now().Unix()})
+				return true
+			case <-subscription.Lagged:
+				c.SSEvent("resync", gin.H{"reason": "client fell too far behind the live stream"})
+				return false
+			case event := <-subscription.Events:
+				// Skip anything already sent during the replay
+				if event.ID <= lastID {
+					return true
+				}
+				c.Render(-1, sse.Event{Id: strconv.FormatUint(uint64(event.ID), 10), Event: event.EntityType, Data: events.ToMessage(event)})
+				lastID = event.ID
+				return true
+			}
+		})
+	}
+}
+
+// StreamEventsWebSocket streams the same changes over a WebSocket connection.
+// Clients resume with the last_event_id query parameter. A {"resync": true} message followed
+// by a close means the client has to reload its data, as with the SSE "resync" event.
+func StreamEventsWebSocket() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		lastEventID := c.Query("last_event_id")
+		lastID, err := parseLastEventID(lastEventID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last_event_id"})
+			return
+		}
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			fmt.Printf("WebSocket upgrade failed for user ID %d: %v\n", userID, err)
+			return
+		}
+		defer conn.Close()
+
+		subscription := events.Subscribe(userID)
+		defer events.Unsubscribe(subscription)
+
+		var backlog []models.Event
+		if lastEventID != "" {
+			backlog, err = events.Since(db.GetDB(), userID, lastID)
+			if err == events.ErrResyncRequired {
+				sendResync(conn, err.Error())
+				return
+			}
+			if err != nil {
+				fmt.Printf("Error loading event log for user ID %d: %v\n", userID, err)
+				return
+			}
+		}
+		for _, event := range backlog {
+			if err := conn.WriteJSON(events.ToMessage(event)); err != nil {
+				return
+			}
+			lastID = event.ID
+		}
+
+		// Drain client frames so close messages are noticed
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-closed:
+				return
+			case <-heartbeat.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+					return
+				}
+			case <-subscription.Lagged:
+				sendResync(conn, "client fell too far behind the live stream")
+				return
+			case event := <-subscription.Events:
+				if event.ID <= lastID {
+					continue
+				}
+				if err := conn.WriteJSON(events.ToMessage(event)); err != nil {
+					return
+				}
+				lastID = event.ID
+			}
+		}
+	}
+}
+
+// parseLastEventID parses a resume cursor; an empty value means the client is not resuming
+func parseLastEventID(value string) (uint, error) {
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
+
+// sendResync tells a WebSocket client to reload its data, then closes the connection
+func sendResync(conn *websocket.Conn, reason string) {
+	if err := conn.WriteJSON(gin.H{"resync": true, "reason": reason}); err != nil {
+		return
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "resync"), time.Now().Add(10*time.Second))
+}
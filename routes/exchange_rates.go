@@ -0,0 +1,204 @@
+// routes/exchange_rates.go
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/currency"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+)
+
+// ExchangeRateRoutes sets up the routes for reading exchange rates and the admin routes for loading them
+func ExchangeRateRoutes(router *gin.Engine) {
+	rateRoutes := router.Group("/exchange-rates")
+	rateRoutes.Use(middleware.AuthMiddleware())
+	{
+		rateRoutes.GET("/", GetExchangeRates())
+		rateRoutes.GET("/convert", ConvertCurrency())
+	}
+
+	adminRoutes := router.Group("/admin/exchange-rates")
+	adminRoutes.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	{
+		adminRoutes.POST("/", CreateExchangeRates())
+		adminRoutes.POST("/import", ImportExchangeRates())
+		adminRoutes.DELETE("/:rate_id", DeleteExchangeRate())
+	}
+}
+
+// GetExchangeRates lists loaded rates, newest first, optionally for one base and/or quote currency
+func GetExchangeRates() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		DB := db.GetDB()
+		query := DB.Model(&models.ExchangeRate{})
+		for _, param := range []string{"base", "quote"} {
+			if value := c.Query(param); value != "" {
+				code, err := currency.Normalize(value)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + ": " + err.Error()})
+					return
+				}
+				query = query.Where(param+" = ?", code)
+			}
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if err != nil || limit < 1 || limit > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+			return
+		}
+
+		var rates []models.ExchangeRate
+		if result := query.Order("effective_date DESC, base, quote").Limit(limit).Find(&rates); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve exchange rates: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"rates": rates})
+	}
+}
+
+// ConvertCurrency converts an amount using the rate effective on the given date (default today)
+func ConvertCurrency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		amount, err := strconv.ParseFloat(c.DefaultQuery("amount", "1"), 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
+			return
+		}
+		from, err := currency.Normalize(c.Query("from"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from: " + err.Error()})
+			return
+		}
+		to, err := currency.Normalize(c.Query("to"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to: " + err.Error()})
+			return
+		}
+		on, err := parseReportDate(c.Query("date"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date. Use YYYY-MM-DD"})
+			return
+		}
+
+		DB := db.GetDB()
+		rate, err := currency.Rate(DB, from, to, on)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"from":   from,
+			"to":     to,
+			"date":   on.Format("2006-01-02"),
+			"rate":   rate,
+			"amount": amount,
+			"result": amount * rate,
+		})
+	}
+}
+
+// CreateExchangeRates stores a batch of rates, replacing existing rates for the same pair and date
+func CreateExchangeRates() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var rateData struct {
+			Rates []struct {
+				Date  string  `json:"date" binding:"required"`
+				Base  string  `json:"base" binding:"required"`
+				Quote string  `json:"quote" binding:"required"`
+				Rate  float64 `json:"rate" binding:"required,gt=0"`
+			} `json:"rates" binding:"required,dive"`
+		}
+		if err := c.ShouldBindJSON(&rateData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		rates := make([]models.ExchangeRate, 0, len(rateData.Rates))
+		for _, rate := range rateData.Rates {
+			date, err := time.Parse("2006-01-02", rate.Date)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date " + rate.Date + ". Use YYYY-MM-DD"})
+				return
+			}
+			rates = append(rates, models.ExchangeRate{Base: rate.Base, Quote: rate.Quote, EffectiveDate: date, Rate: rate.Rate, Source: "api"})
+		}
+
+		DB := db.GetDB()
+		if err := currency.Save(DB, rates); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to save exchange rates: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"saved": len(rates)})
+	}
+}
+
+// ImportExchangeRates loads rates from an uploaded CSV file (multipart field "file")
+// with the columns date,base,quote,rate
+func ImportExchangeRates() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file: " + err.Error()})
+			return
+		}
+		defer file.Close()
+
+		rates, err := currency.ParseCSV(file, fileHeader.Filename)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CSV: " + err.Error()})
+			return
+		}
+
+		DB := db.GetDB()
+		if err := currency.Save(DB, rates); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to save exchange rates: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"saved": len(rates)})
+	}
+}
+
+// DeleteExchangeRate removes a single rate
+func DeleteExchangeRate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		DB := db.GetDB()
+		result := DB.Delete(&models.ExchangeRate{}, c.Param("rate_id"))
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exchange rate: " + result.Error.Error()})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exchange rate not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Exchange rate deleted successfully"})
+	}
+}
+
+// parseReportDate parses a YYYY-MM-DD report date as the end of that day (default now)
+func parseReportDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now(), nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return date.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
@@ -0,0 +1,230 @@
+// routes/insurance.go
+package routes
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/currency"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+)
+
+// ReportRoutes sets up the routes for generated reports
+func ReportRoutes(router *gin.Engine) {
+	reportRoutes := router.Group("/reports")
+	reportRoutes.Use(middleware.AuthMiddleware())
+	{
+		reportRoutes.GET("/insurance", GetInsuranceReport())
+	}
+}
+
+// insuranceItem is one line of the insurance report
+type insuranceItem struct {
+	ItemID        uint       `json:"item_id"`
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	ImageUrl      string     `json:"image_url"`
+	Quantity      int        `json:"quantity"`
+	SerialNumbers []string   `json:"serial_numbers"`
+	Vendor        string     `json:"vendor"`
+	PurchaseDate  *time.Time `json:"purchase_date"`
+	PurchasePrice float64    `json:"purchase_price"`
+	UnitValue     float64    `json:"unit_value"`
+	TotalValue    float64    `json:"total_value"`
+	Currency      string     `json:"currency"`
+	ReceiptUrl    string     `json:"receipt_url"`
+}
+
+// insuranceLocation groups the report lines stored in one location
+type insuranceLocation struct {
+	LocationID uint               `json:"location_id"`
+	Name       string             `json:"name"`
+	Items      []insuranceItem    `json:"items"`
+	Totals     map[string]float64 `json:"totals"`
+}
+
+// GetInsuranceReport lists everything the user owns with serial numbers, purchase data
+// and depreciated value, grouped by location.
+//
+//	format=html|json          html (default) is print-ready so it can be saved as PDF from a browser
+//	location_id=<id>          only one location
+//	currency=<ISO-4217 code>  convert values at today's rates
+func GetInsuranceReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		format := c.DefaultQuery("format", "html")
+		if format != "html" && format != "json" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be html or json"})
+			return
+		}
+
+		reportingCurrency := ""
+		if value := c.Query("currency"); value != "" {
+			code, err := currency.Normalize(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency: " + err.Error()})
+				return
+			}
+			reportingCurrency = code
+		}
+
+		DB := db.GetDB()
+		query := DB.Preload("Location").Where("user_id = ?", userID)
+		if locationID := c.Query("location_id"); locationID != "" {
+			query = query.Where("location_id = ?", locationID)
+		}
+		var items []models.Item
+		if result := query.Order("name").Find(&items); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve items: " + result.Error.Error()})
+			return
+		}
+
+		// Serial numbers of the assets still in service
+		itemIDs := make([]uint, 0, len(items))
+		for _, item := range items {
+			itemIDs = append(itemIDs, item.ID)
+		}
+		serials := make(map[uint][]string)
+		if len(itemIDs) > 0 {
+			var assets []models.Asset
+			if result := DB.Where("item_id IN ? AND status <> ?", itemIDs, models.AssetRetired).Order("serial_number").Find(&assets); result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve assets: " + result.Error.Error()})
+				return
+			}
+			for _, asset := range assets {
+				serials[asset.ItemID] = append(serials[asset.ItemID], asset.SerialNumber)
+			}
+		}
+
+		now := time.Now()
+		rates := make(map[string]float64)
+		locations := make(map[uint]*insuranceLocation)
+		totals := make(map[string]float64)
+		for _, item := range items {
+			line := insuranceItem{
+				ItemID:        item.ID,
+				Name:          item.Name,
+				Description:   item.Description,
+				ImageUrl:      item.ImageUrl,
+				Quantity:      item.Quantity,
+				SerialNumbers: serials[item.ID],
+				Vendor:        item.Vendor,
+				PurchaseDate:  item.PurchaseDate,
+				PurchasePrice: item.PurchasePrice,
+				UnitValue:     item.CurrentValue,
+				Currency:      itemCurrency(item),
+				ReceiptUrl:    item.ReceiptUrl,
+			}
+			if reportingCurrency != "" && line.Currency != reportingCurrency {
+				rate, ok := rates[line.Currency]
+				if !ok {
+					var err error
+					if rate, err = currency.Rate(DB, line.Currency, reportingCurrency, now); err != nil {
+						c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to convert values: " + err.Error()})
+						return
+					}
+					rates[line.Currency] = rate
+				}
+				line.PurchasePrice *= rate
+				line.UnitValue *= rate
+				line.Currency = reportingCurrency
+			}
+			line.TotalValue = line.UnitValue * float64(line.Quantity)
+
+			group, ok := locations[item.LocationID]
+			if !ok {
+				name := item.Location.Name
+				if name == "" {
+					name = "Unassigned"
+				}
+				group = &insuranceLocation{LocationID: item.LocationID, Name: name, Totals: make(map[string]float64)}
+				locations[item.LocationID] = group
+			}
+			group.Items = append(group.Items, line)
+			group.Totals[line.Currency] += line.TotalValue
+			totals[line.Currency] += line.TotalValue
+		}
+
+		report := make([]*insuranceLocation, 0, len(locations))
+		for _, group := range locations {
+			report = append(report, group)
+		}
+		sort.Slice(report, func(i, j int) bool { return report[i].Name < report[j].Name })
+
+		if format == "json" {
+			c.JSON(http.StatusOK, gin.H{"generated_at": now, "locations": report, "totals": totals})
+			return
+		}
+
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := insuranceTemplate.Execute(c.Writer, gin.H{"GeneratedAt": now, "Locations": report, "Totals": totals}); err != nil {
+			fmt.Printf("Failed to render insurance report: %v\n", err)
+		}
+	}
+}
+
+// insuranceTemplate renders the insurance report as a printable HTML page
+var insuranceTemplate = template.Must(template.New("insurance").Funcs(template.FuncMap{
+	"money": func(amount float64) string { return fmt.Sprintf("%.2f", amount) },
+	"date": func(date *time.Time) string {
+		if date == nil {
+			return ""
+		}
+		return date.Format("2006-01-02")
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Insurance inventory report</title>
+<style>
+body { font-family: sans-serif; font-size: 12px; margin: 24px; }
+h1 { font-size: 20px; }
+h2 { font-size: 16px; margin-top: 32px; page-break-after: avoid; }
+table { width: 100%; border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 6px; text-align: left; vertical-align: top; }
+td.number { text-align: right; }
+img { max-width: 120px; max-height: 90px; }
+tr { page-break-inside: avoid; }
+</style>
+</head>
+<body>
+<h1>Insurance inventory report</h1>
+<p>Generated {{.GeneratedAt.Format "2006-01-02 15:04"}}</p>
+<p>Total value:{{range $currency, $total := .Totals}} {{money $total}} {{$currency}}{{end}}</p>
+{{range .Locations}}
+<h2>{{.Name}}</h2>
+<table>
+<tr><th>Photo</th><th>Item</th><th>Serial numbers</th><th>Vendor</th><th>Purchased</th><th>Qty</th><th>Unit price</th><th>Unit value</th><th>Total value</th><th>Receipt</th></tr>
+{{range .Items}}
+<tr>
+<td>{{if .ImageUrl}}<img src="{{.ImageUrl}}" alt="{{.Name}}">{{end}}</td>
+<td><strong>{{.Name}}</strong><br>{{.Description}}</td>
+<td>{{range .SerialNumbers}}{{.}}<br>{{end}}</td>
+<td>{{.Vendor}}</td>
+<td>{{date .PurchaseDate}}</td>
+<td class="number">{{.Quantity}}</td>
+<td class="number">{{money .PurchasePrice}} {{.Currency}}</td>
+<td class="number">{{money .UnitValue}} {{.Currency}}</td>
+<td class="number">{{money .TotalValue}} {{.Currency}}</td>
+<td>{{if .ReceiptUrl}}<a href="{{.ReceiptUrl}}">Receipt</a>{{end}}</td>
+</tr>
+{{end}}
+</table>
+<p>Location total:{{range $currency, $total := .Totals}} {{money $total}} {{$currency}}{{end}}</p>
+{{end}}
+</body>
+</html>
+`))
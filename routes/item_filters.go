@@ -0,0 +1,302 @@
+// routes/item_filters.go
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// itemFilter holds the item list filters shared by GetAllItems and GetItemFacets:
+//
+//	category_id=<id>        items in the category or any of its subcategories
+//	tags=winter,camping     items carrying the given tags
+//	tag_match=any|all       whether items need any (default) or all of the tags
+//	location_id=<id>        items stored in a location
+//	barcode=<code>          items with the scanned barcode
+//	field.<key>=<value>     items whose custom field equals the value
+//	field.<key>.min=<value> items whose custom field is at least the value (also .max)
+//	sort=<column>           name, created_at, updated_at, quantity or field.<key>; prefix with - for descending
+type itemFilter struct {
+	UserID      uint
+	CategoryIDs []uint
+	Tags        []string
+	MatchAll    bool
+	LocationID  *uint
+	Barcode     string
+	Fields      []fieldCondition
+	Sort        *clause.OrderBy
+}
+
+// fieldCondition compares a custom field value on items
+type fieldCondition struct {
+	Field    models.CustomField
+	Operator string // "=", ">=" or "<="
+	Value    interface{}
+}
+
+// itemSortColumns are the built-in columns items can be sorted by
+var itemSortColumns = map[string]bool{
+	"name":       true,
+	"created_at": true,
+	"updated_at": true,
+	"quantity":   true,
+}
+
+// parseItemFilter reads the item filters from the query string
+func parseItemFilter(c *gin.Context, DB *gorm.DB, userID uint) (itemFilter, error) {
+	filter := itemFilter{UserID: userID}
+
+	if value := c.Query("category_id"); value != "" {
+		categoryID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid category_id")
+		}
+		ids, err := categorySubtreeIDs(DB, userID, uint(categoryID))
+		if err != nil {
+			return filter, err
+		}
+		if len(ids) == 0 {
+			return filter, fmt.Errorf("category not found")
+		}
+		filter.CategoryIDs = ids
+	}
+
+	if value := c.Query("tags"); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			if name = normalizeTagName(name); name != "" {
+				filter.Tags = append(filter.Tags, name)
+			}
+		}
+	}
+
+	switch c.DefaultQuery("tag_match", "any") {
+	case "any":
+	case "all":
+		filter.MatchAll = true
+	default:
+		return filter, fmt.Errorf("tag_match must be any or all")
+	}
+
+	if value := c.Query("location_id"); value != "" {
+		locationID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid location_id")
+		}
+		id := uint(locationID)
+		filter.LocationID = &id
+	}
+
+	filter.Barcode = strings.TrimSpace(c.Query("barcode"))
+
+	// Custom field filters and sorting need the user's field definitions to know each key's type
+	fields := make(map[string]models.CustomField)
+	sort := c.Query("sort")
+	needsFields := strings.HasPrefix(strings.TrimPrefix(sort, "-"), "field.")
+	for param := range c.Request.URL.Query() {
+		if strings.HasPrefix(param, "field.") {
+			needsFields = true
+		}
+	}
+	if needsFields {
+		var definitions []models.CustomField
+		if err := DB.Where("user_id = ?", userID).Find(&definitions).Error; err != nil {
+			return filter, err
+		}
+		for _, field := range definitions {
+			fields[field.Key] = field
+		}
+	}
+
+	for param, values := range c.Request.URL.Query() {
+		if !strings.HasPrefix(param, "field.") || len(values) == 0 {
+			continue
+		}
+		key := strings.TrimPrefix(param, "field.")
+		operator := "="
+		if strings.HasSuffix(key, ".min") {
+			key, operator = strings.TrimSuffix(key, ".min"), ">="
+		} else if strings.HasSuffix(key, ".max") {
+			key, operator = strings.TrimSuffix(key, ".max"), "<="
+		}
+
+		field, ok := fields[key]
+		if !ok {
+			return filter, fmt.Errorf("unknown custom field %q", key)
+		}
+		if operator != "=" && field.Type != models.FieldNumber && field.Type != models.FieldDate {
+			return filter, fmt.Errorf("range filters only work on number and date fields")
+		}
+		value, err := normalizeCustomValue(field, values[0])
+		if err != nil {
+			return filter, fmt.Errorf("field %q: %v", key, err)
+		}
+		filter.Fields = append(filter.Fields, fieldCondition{Field: field, Operator: operator, Value: value})
+	}
+
+	if sort != "" {
+		descending := strings.HasPrefix(sort, "-")
+		column := strings.TrimPrefix(sort, "-")
+		direction := " ASC NULLS LAST"
+		if descending {
+			direction = " DESC NULLS LAST"
+		}
+
+		if strings.HasPrefix(column, "field.") {
+			field, ok := fields[strings.TrimPrefix(column, "field.")]
+			if !ok {
+				return filter, fmt.Errorf("unknown custom field %q", strings.TrimPrefix(column, "field."))
+			}
+			filter.Sort = &clause.OrderBy{Expression: clause.Expr{
+				SQL:                customFieldExpr(field) + direction,
+				Vars:               []interface{}{field.Key},
+				WithoutParentheses: true,
+			}}
+		} else if itemSortColumns[column] {
+			filter.Sort = &clause.OrderBy{Expression: clause.Expr{SQL: "items." + column + direction}}
+		} else {
+			return filter, fmt.Errorf("cannot sort by %q", column)
+		}
+	}
+
+	return filter, nil
+}
+
+// apply adds the filter conditions to a query on the items table.
+// Columns are qualified so the query can be joined with other tables.
+func (filter itemFilter) apply(query *gorm.DB) *gorm.DB {
+	query = query.Where("items.user_id = ?", filter.UserID)
+
+	if len(filter.CategoryIDs) > 0 {
+		query = query.Where("items.category_id IN ?", filter.CategoryIDs)
+	}
+
+	if len(filter.Tags) > 0 {
+		if filter.MatchAll {
+			query = query.Where(`items.id IN (
+				SELECT item_tags.item_id FROM item_tags JOIN tags ON tags.id = item_tags.tag_id
+				WHERE tags.user_id = ? AND tags.name IN ?
+				GROUP BY item_tags.item_id HAVING COUNT(DISTINCT tags.id) = ?)`, filter.UserID, filter.Tags, len(filter.Tags))
+		} else {
+			query = query.Where(`items.id IN (
+				SELECT item_tags.item_id FROM item_tags JOIN tags ON tags.id = item_tags.tag_id
+				WHERE tags.user_id = ? AND tags.name IN ?)`, filter.UserID, filter.Tags)
+		}
+	}
+
+	if filter.LocationID != nil {
+		query = query.Where("items.location_id = ?", *filter.LocationID)
+	}
+
+	if filter.Barcode != "" {
+		query = query.Where("items.barcode = ?", filter.Barcode)
+	}
+
+	for _, condition := range filter.Fields {
+		query = query.Where(customFieldExpr(condition.Field)+" "+condition.Operator+" ?", condition.Field.Key, condition.Value)
+	}
+
+	return query
+}
+
+// order applies the requested sort (used for lists, not for aggregate queries like facets)
+func (filter itemFilter) order(query *gorm.DB) *gorm.DB {
+	if filter.Sort == nil {
+		return query
+	}
+	return query.Clauses(*filter.Sort)
+}
+
+// customFieldExpr is the SQL expression reading a custom field as its native type.
+// The field key is bound as the single placeholder.
+func customFieldExpr(field models.CustomField) string {
+	switch field.Type {
+	case models.FieldNumber:
+		return "(items.custom_fields ->> ?)::numeric"
+	case models.FieldDate:
+		return "(items.custom_fields ->> ?)::date"
+	case models.FieldBoolean:
+		return "(items.custom_fields ->> ?)::boolean"
+	}
+	return "(items.custom_fields ->> ?)"
+}
+
+// facetCount is one bucket of a facet
+type facetCount struct {
+	ID    *uint  `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// GetItemFacets returns item counts per category, tag and location for the current filter
+func GetItemFacets() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		DB := db.GetDB()
+		filter, err := parseItemFilter(c, DB, userID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filter: " + err.Error()})
+			return
+		}
+
+		var total int64
+		if result := filter.apply(DB.Table("items")).Count(&total); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count items: " + result.Error.Error()})
+			return
+		}
+
+		categories := []facetCount{}
+		if result := filter.apply(DB.Table("items")).
+			Select("items.category_id AS id, COALESCE(categories.name, 'Uncategorised') AS name, COUNT(*) AS count").
+			Joins("LEFT JOIN categories ON categories.id = items.category_id").
+			Group("items.category_id, categories.name").
+			Order("count DESC").
+			Scan(&categories); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count categories: " + result.Error.Error()})
+			return
+		}
+
+		tags := []facetCount{}
+		if result := filter.apply(DB.Table("items")).
+			Select("tags.id AS id, tags.name AS name, COUNT(*) AS count").
+			Joins("JOIN item_tags ON item_tags.item_id = items.id").
+			Joins("JOIN tags ON tags.id = item_tags.tag_id").
+			Group("tags.id, tags.name").
+			Order("count DESC").
+			Scan(&tags); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count tags: " + result.Error.Error()})
+			return
+		}
+
+		locations := []facetCount{}
+		if result := filter.apply(DB.Table("items")).
+			Select("items.location_id AS id, COALESCE(locations.name, 'Unassigned') AS name, COUNT(*) AS count").
+			Joins("LEFT JOIN locations ON locations.id = items.location_id").
+			Group("items.location_id, locations.name").
+			Order("count DESC").
+			Scan(&locations); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count locations: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"total":      total,
+			"categories": categories,
+			"tags":       tags,
+			"locations":  locations,
+		})
+	}
+}
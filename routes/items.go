@@ -1,8 +1,12 @@
 package routes
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/sidhant-sriv/inventory-api/currency"
 	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/events"
 	"github.com/sidhant-sriv/inventory-api/middleware"
 	"github.com/sidhant-sriv/inventory-api/models"
 	"gorm.io/gorm"
@@ -12,6 +16,14 @@ import (
 	"time"
 )
 
+// itemDeleteConflicts are the errors deleteItem refuses an item with, and what to tell the user
+var itemDeleteConflicts = map[error]string{
+	errItemInKit:       "Cannot delete an item that is a component of a kit",
+	errItemCheckedOut:  "Cannot delete an item with assets that are checked out",
+	errItemInStocktake: "Cannot delete an item that is being counted in an open stocktake",
+	errItemOnOrder:     "Cannot delete an item on an open purchase order",
+}
+
 // ItemRoutes sets up the routes for item-related operations
 func ItemRoutes(router *gin.Engine) {
 	// All item routes should be protected
@@ -28,6 +40,7 @@ func ItemRoutes(router *gin.Engine) {
 		itemRoutes.GET("/date", GetItemByDate())
 		itemRoutes.GET("/date-range", GetItemByDateRange())
 		itemRoutes.GET("/page", GetItemByPage())
+		itemRoutes.GET("/facets", GetItemFacets())
 		itemRoutes.GET("/location/:location_id/date", GetItemByLocationAndDate())
 	}
 }
@@ -54,14 +67,35 @@ func CreateItem() gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
 			return
 		}
+		item.ID = 0
 		item.UserID = id
+		item.ReceiptUrl = ""      // set through the receipt upload endpoint
+		item.ReservedQuantity = 0 // maintained by the reservation endpoints
 
-		// Create the item in database
+		// Create the item together with its opening stock movement
+		var outbox events.Outbox
 		DB := db.GetDB()
-		if result := DB.Create(&item); result.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create item: " + result.Error.Error()})
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := validateItem(tx, item.UserID, &item); err != nil {
+				return err
+			}
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+			if err := recordStockChange(tx, item, 0, models.MovementOpening, ""); err != nil {
+				return err
+			}
+			return outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionCreated, item)
+		})
+		if validationErr, ok := err.(*itemValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
 			return
 		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create item: " + err.Error()})
+			return
+		}
+		outbox.Broadcast()
 
 		c.JSON(http.StatusCreated, gin.H{"item": item})
 	}
@@ -97,16 +131,22 @@ func GetItem() gin.HandlerFunc {
 			return
 		}
 
-		if item.UserID != id {
+		// Owners and users the item (or its location) is shared with can view it
+		permission, err := itemPermission(DB, id, item)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check access: " + err.Error()})
+			return
+		}
+		if permission == "" {
 			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to view this item"})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"item": item})
+		c.JSON(http.StatusOK, gin.H{"item": item, "permission": permission})
 	}
 }
 
-// GetAllItems retrieves all items for the authenticated user
+// GetAllItems retrieves all items for the authenticated user (see itemFilter for query filters)
 func GetAllItems() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var items []models.Item
@@ -124,9 +164,14 @@ func GetAllItems() gin.HandlerFunc {
 			return
 		}
 
-		// Get all items for the user
+		// Get all items for the user, narrowed by any category/tag/location filters
 		DB := db.GetDB()
-		if result := DB.Preload(clause.Associations).Where("user_id = ?", id).Find(&items); result.Error != nil {
+		filter, err := parseItemFilter(c, DB, id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filter: " + err.Error()})
+			return
+		}
+		if result := filter.order(filter.apply(DB.Preload(clause.Associations))).Find(&items); result.Error != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve items: " + result.Error.Error()})
 			return
 		}
@@ -165,28 +210,59 @@ func UpdateItem() gin.HandlerFunc {
 			return
 		}
 
-		// Verify user owns this item
-		if item.UserID != id {
+		// Verify user owns this item or has it shared with them as an editor
+		permission, err := itemPermission(DB, id, item)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check access: " + err.Error()})
+			return
+		}
+		if permission != permissionOwner && permission != models.ShareEditor {
 			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to update this item"})
 			return
 		}
 
-		// Store the current UserID before binding JSON
-		originalUserID := item.UserID
-
-		if err := c.ShouldBindJSON(&item); err != nil {
+		body, err := c.GetRawData()
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		// Prevent changing the user ID
-		item.UserID = originalUserID
-
-		// Update the item in the database
-		if result := DB.Save(&item); result.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item: " + result.Error.Error()})
+		// The body is bound onto the row re-read under a lock, so a concurrent stock change is
+		// neither overwritten nor missing from the adjustment logged for this edit.
+		// custom_fields are merged into the existing values; send null to remove one.
+		// Shared editors pick from the owner's categories, like suppliers.
+		var outbox events.Outbox
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, item.ID).Error; err != nil {
+				return err
+			}
+			stored := item
+			if err := binding.JSON.BindBody(body, &item); err != nil {
+				return &itemValidationError{err.Error()}
+			}
+			// Only the owner can move the item
+			if permission != permissionOwner {
+				item.LocationID = stored.LocationID
+			}
+			return updateItem(tx, &outbox, stored, &item, "")
+		})
+		if validationErr, ok := err.(*itemValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+		if err == errBelowReserved {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Quantity can't be lower than the %d units reserved", item.ReservedQuantity)})
+			return
+		}
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item: " + err.Error()})
 			return
 		}
+		outbox.Broadcast()
 
 		c.JSON(http.StatusOK, gin.H{"item": item})
 	}
@@ -228,11 +304,25 @@ func DeleteItem() gin.HandlerFunc {
 			return
 		}
 
-		// Delete the item from the database
-		if result := DB.Delete(&item); result.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete item: " + result.Error.Error()})
+		// Delete the item from the database along with the records that hang off it
+		var outbox events.Outbox
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			// Published first, while the shares that say who else sees it still exist
+			if err := outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionDeleted, item); err != nil {
+				return err
+			}
+			return deleteItem(tx, item)
+		})
+		if message, refused := itemDeleteConflicts[err]; refused {
+			c.JSON(http.StatusConflict, gin.H{"error": message})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete item: " + err.Error()})
 			return
 		}
+		removeReceiptFile(item)
+		outbox.Broadcast()
 
 		c.JSON(http.StatusOK, gin.H{"message": "Item deleted successfully"})
 	}
@@ -257,9 +347,24 @@ func GetItemByLocation() gin.HandlerFunc {
 			return
 		}
 
-		// Get all items for the location AND the authenticated user
+		// Get the user's own items in the location, plus the location owner's items if the
+		// location (or one enclosing it) is shared with the user, plus items shared individually
 		DB := db.GetDB()
-		if result := DB.Where("location_id = ? AND user_id = ?", locationID, id).Find(&items); result.Error != nil {
+		owners := []uint{id}
+		var location models.Location
+		if result := DB.First(&location, locationID); result.Error == nil && location.UserID != id && location.UserID != 0 {
+			permission, err := locationPermission(DB, id, location)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check access: " + err.Error()})
+				return
+			}
+			if permission != "" {
+				owners = append(owners, location.UserID)
+			}
+		}
+		if result := DB.Where("location_id = ?", locationID).
+			Where("(user_id IN ? OR id IN (SELECT item_id FROM shares WHERE user_id = ? AND item_id IS NOT NULL))", owners, id).
+			Find(&items); result.Error != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve items: " + result.Error.Error()})
 			return
 		}
@@ -513,3 +618,213 @@ func GetItemByLocationAndDate() gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"items": items})
 	}
 }
+
+// findUserItem loads the item named in the URL if it belongs to the authenticated user.
+// It writes the error response itself and reports whether the handler should continue.
+func findUserItem(c *gin.Context) (models.Item, bool) {
+	var item models.Item
+
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return item, false
+	}
+
+	DB := db.GetDB()
+	if result := DB.First(&item, c.Param("item_id")); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve item: " + result.Error.Error()})
+		}
+		return item, false
+	}
+
+	if item.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access this item"})
+		return item, false
+	}
+	return item, true
+}
+
+// deleteItem removes an item with its tag links, bill of materials, lots, reservations, shares,
+// share links, assets, alerts, maintenance history and closed stocktake and purchase order lines.
+// Items that are still in use are refused with one of the errors in itemDeleteConflicts.
+func deleteItem(tx *gorm.DB, item models.Item) error {
+	if err := checkItemNotInUse(tx, item); err != nil {
+		return err
+	}
+	if err := removeKitComponents(tx, item); err != nil {
+		return err
+	}
+	if err := tx.Where("asset_id IN (?)", tx.Model(&models.Asset{}).Select("id").Where("item_id = ?", item.ID)).Delete(&models.Checkout{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("item_id = ?", item.ID).Delete(&models.Asset{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("item_id = ?", item.ID).Delete(&models.Alert{}).Error; err != nil {
+		return err
+	}
+	itemLines := tx.Model(&models.StocktakeLine{}).Select("id").Where("item_id = ?", item.ID)
+	if err := tx.Where("line_id IN (?)", itemLines).Delete(&models.StocktakeCount{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("item_id = ?", item.ID).Delete(&models.StocktakeLine{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("item_id = ?", item.ID).Delete(&models.PurchaseOrderLine{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("item_id = ?", item.ID).Delete(&models.Reservation{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("item_id = ?", item.ID).Delete(&models.Lot{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("item_id = ?", item.ID).Delete(&models.MaintenanceLog{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("item_id = ?", item.ID).Delete(&models.MaintenanceTask{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("item_id = ?", item.ID).Delete(&models.Share{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("item_id = ?", item.ID).Delete(&models.ShareLink{}).Error; err != nil {
+		return err
+	}
+	return tx.Select("Tags").Delete(&item).Error
+}
+
+// checkItemNotInUse refuses to delete an item with assets out on loan, or one that an open
+// stocktake or purchase order still refers to
+func checkItemNotInUse(tx *gorm.DB, item models.Item) error {
+	var count int64
+	if err := tx.Model(&models.Checkout{}).Joins("JOIN assets ON assets.id = checkouts.asset_id").
+		Where("assets.item_id = ? AND checkouts.returned_at IS NULL", item.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errItemCheckedOut
+	}
+
+	if err := tx.Model(&models.StocktakeLine{}).Joins("JOIN stocktake_sessions ON stocktake_sessions.id = stocktake_lines.session_id").
+		Where("stocktake_lines.item_id = ? AND stocktake_sessions.status = ?", item.ID, models.StocktakeOpen).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errItemInStocktake
+	}
+
+	openStatuses := []string{models.PurchaseOrderDraft, models.PurchaseOrderOrdered, models.PurchaseOrderPartiallyReceived}
+	if err := tx.Model(&models.PurchaseOrderLine{}).Joins("JOIN purchase_orders ON purchase_orders.id = purchase_order_lines.purchase_order_id").
+		Where("purchase_order_lines.item_id = ? AND purchase_orders.status IN ?", item.ID, openStatuses).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errItemOnOrder
+	}
+	return nil
+}
+
+// clearItemAssociations drops nested records bound from a request body so saving an item
+// never creates or re-parents them; lots, tags and categories have their own endpoints and
+// an item is moved by its location_id alone.
+func clearItemAssociations(item *models.Item) {
+	item.Location = models.Location{}
+	item.Lots = nil
+	item.Tags = nil
+	item.Category = nil
+	item.Supplier = nil
+}
+
+// updateItem saves an edited item over its stored row, which the caller has locked. The body
+// can't change the item's identity, owner, receipt or reservations, and a quantity change is
+// logged against the locked quantity.
+func updateItem(tx *gorm.DB, outbox *events.Outbox, stored models.Item, item *models.Item, reference string) error {
+	item.ID = stored.ID
+	item.UserID = stored.UserID
+	item.ReceiptUrl = stored.ReceiptUrl
+	item.ReservedQuantity = stored.ReservedQuantity
+	if err := validateItem(tx, item.UserID, item); err != nil {
+		return err
+	}
+	// reserved_quantity is left alone so concurrent reservations aren't overwritten
+	if err := tx.Omit("ReservedQuantity").Save(item).Error; err != nil {
+		return err
+	}
+	if err := recordStockChange(tx, *item, stored.Quantity, models.MovementAdjustment, reference); err != nil {
+		return err
+	}
+	return outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionUpdated, *item)
+}
+
+// itemValidationError is an invalid item field; handlers answer it with 400
+type itemValidationError struct {
+	message string
+}
+
+func (e *itemValidationError) Error() string {
+	return e.message
+}
+
+// validateItem prepares an item from a request body to be created or saved, and checks it. The
+// location (unless unchanged), category and supplier must belong to userID, the item's owner.
+// An existing item's stored row is locked, custom fields its old category defined are dropped
+// and its quantity must still cover what is reserved; it can't change at all if the item has
+// lots. Bad input is returned as an *itemValidationError and a quantity below the reservations
+// as errBelowReserved.
+func validateItem(tx *gorm.DB, userID uint, item *models.Item) error {
+	item.Currency = itemCurrency(*item)
+	clearItemAssociations(item)
+
+	var stored models.Item
+	if item.ID != 0 {
+		if err := checkReservedQuantity(tx, item); err != nil {
+			return err
+		}
+		if err := tx.Select("id", "location_id", "category_id", "quantity").First(&stored, item.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	if (item.ID == 0 || item.LocationID != stored.LocationID) && !userCanUseLocation(tx, userID, item.LocationID) {
+		return &itemValidationError{"Location not found"}
+	}
+	if !userOwnsCategory(tx, userID, item.CategoryID) {
+		return &itemValidationError{"Category not found"}
+	}
+	if !userOwnsSupplier(tx, userID, item.SupplierID) {
+		return &itemValidationError{"Supplier not found"}
+	}
+	if _, err := currency.Normalize(item.Currency); err != nil {
+		return &itemValidationError{"Invalid currency: " + err.Error()}
+	}
+	if err := validateDepreciation(item); err != nil {
+		return &itemValidationError{"Invalid depreciation: " + err.Error()}
+	}
+	if err := validateWarranty(*item); err != nil {
+		return &itemValidationError{"Invalid warranty: " + err.Error()}
+	}
+
+	if item.ID != 0 {
+		// The quantity of an item with lots is the sum of its lots and follows them
+		if item.Quantity != stored.Quantity {
+			var lotCount int64
+			if err := tx.Model(&models.Lot{}).Where("item_id = ?", item.ID).Count(&lotCount).Error; err != nil {
+				return err
+			}
+			if lotCount > 0 {
+				return &itemValidationError{"Quantity of an item with lots can't be set directly; change its lots through /items/:item_id/lots instead"}
+			}
+		}
+		if err := dropStaleCustomFields(tx, item, stored.CategoryID); err != nil {
+			return err
+		}
+	}
+	if err := validateItemCustomFields(tx, item); err != nil {
+		return &itemValidationError{"Invalid custom fields: " + err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// routes/items_test.go
+package routes
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// nestedLocationItem is an item payload that names one location and nests another one,
+// owned by a different user, that GORM would otherwise save along with the item
+const nestedLocationItem = `{
+	"name": "Drill",
+	"location_id": 3,
+	"quantity": 1,
+	"location": {"id": 7, "user_id": 2, "name": "Someone else's shed"}
+}`
+
+// savedTables creates an item without a database and returns the tables written to
+func savedTables(t *testing.T, item *models.Item) []string {
+	t.Helper()
+	DB, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("opening dry-run database: %v", err)
+	}
+
+	var tables []string
+	record := func(tx *gorm.DB) { tables = append(tables, tx.Statement.Table) }
+	if err := DB.Callback().Create().Before("gorm:create").Register("test:record_table", record); err != nil {
+		t.Fatalf("registering callback: %v", err)
+	}
+	if err := DB.Create(item).Error; err != nil {
+		t.Fatalf("creating item: %v", err)
+	}
+	return tables
+}
+
+func TestClearItemAssociationsDropsNestedLocation(t *testing.T) {
+	var item models.Item
+	if err := json.Unmarshal([]byte(nestedLocationItem), &item); err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+	clearItemAssociations(&item)
+
+	if item.Location.ID != 0 || item.Location.UserID != 0 || item.Location.Name != "" {
+		t.Errorf("Location = {ID:%d UserID:%d Name:%q}, want it cleared", item.Location.ID, item.Location.UserID, item.Location.Name)
+	}
+	if item.LocationID != 3 {
+		t.Errorf("LocationID = %d, want 3 from location_id", item.LocationID)
+	}
+	for _, table := range savedTables(t, &item) {
+		if table == "locations" {
+			t.Errorf("saving the item wrote to locations")
+		}
+	}
+}
@@ -0,0 +1,321 @@
+// routes/kits.go
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/events"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errNotAKit is returned when assembling or disassembling an item without components
+var errNotAKit = fmt.Errorf("item has no components")
+
+// errItemInKit is returned when deleting an item that other kits are built from
+var errItemInKit = fmt.Errorf("item is a component of a kit")
+
+// KitRoutes sets up the routes for kits and their bills of materials
+func KitRoutes(router *gin.Engine) {
+	kitRoutes := router.Group("/items")
+	kitRoutes.Use(middleware.AuthMiddleware())
+	{
+		kitRoutes.GET("/:item_id/components", GetKitComponents())
+		kitRoutes.PUT("/:item_id/components", SetKitComponents())
+		kitRoutes.POST("/:item_id/assemble", AssembleKit())
+		kitRoutes.POST("/:item_id/disassemble", DisassembleKit())
+	}
+}
+
+// kitQuantityRequest is the payload for assembling or disassembling kits
+type kitQuantityRequest struct {
+	Quantity int `json:"quantity" binding:"required,min=1"`
+}
+
+// GetKitComponents lists a kit's bill of materials and how many kits the available stock can build
+func GetKitComponents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		var components []models.KitComponent
+		DB := db.GetDB()
+		if result := DB.Preload("Component").Where("kit_item_id = ?", item.ID).Order("id").Find(&components); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve components: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"item_id":    item.ID,
+			"components": components,
+			"buildable":  kitBuildable(components),
+		})
+	}
+}
+
+// SetKitComponents replaces a kit's bill of materials. An empty list turns the kit back into a plain item.
+func SetKitComponents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var componentData struct {
+			Components []struct {
+				ItemID   uint `json:"item_id" binding:"required"`
+				Quantity int  `json:"quantity" binding:"required,min=1"`
+			} `json:"components" binding:"dive"`
+		}
+		if err := c.ShouldBindJSON(&componentData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		componentIDs := make([]uint, 0, len(componentData.Components))
+		seen := make(map[uint]bool)
+		for _, component := range componentData.Components {
+			if component.ItemID == item.ID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "A kit cannot contain itself"})
+				return
+			}
+			if seen[component.ItemID] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Component %d is listed more than once", component.ItemID)})
+				return
+			}
+			seen[component.ItemID] = true
+			componentIDs = append(componentIDs, component.ItemID)
+		}
+
+		DB := db.GetDB()
+		if len(componentIDs) > 0 {
+			var count int64
+			if result := DB.Model(&models.Item{}).Where("id IN ? AND user_id = ?", componentIDs, item.UserID).Count(&count); result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check components: " + result.Error.Error()})
+				return
+			}
+			if int(count) != len(componentIDs) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Components must be your own items"})
+				return
+			}
+
+			// A kit cannot be built from anything that is (directly or indirectly) built from the kit
+			subtree, err := kitSubtreeIDs(DB, componentIDs)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check kit hierarchy: " + err.Error()})
+				return
+			}
+			for _, id := range subtree {
+				if id == item.ID {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Components cannot include the kit or anything built from it"})
+					return
+				}
+			}
+		}
+
+		components := make([]models.KitComponent, 0, len(componentData.Components))
+		for _, component := range componentData.Components {
+			components = append(components, models.KitComponent{KitItemID: item.ID, ComponentItemID: component.ItemID, Quantity: component.Quantity})
+		}
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("kit_item_id = ?", item.ID).Delete(&models.KitComponent{}).Error; err != nil {
+				return err
+			}
+			if len(components) == 0 {
+				return nil
+			}
+			return tx.Omit(clause.Associations).Create(&components).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save components: " + err.Error()})
+			return
+		}
+
+		if result := DB.Preload("Component").Where("kit_item_id = ?", item.ID).Order("id").Find(&components); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve components: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"item_id":    item.ID,
+			"components": components,
+			"buildable":  kitBuildable(components),
+		})
+	}
+}
+
+// AssembleKit builds kits from their components, taking the components out of stock
+func AssembleKit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		changeKitStock(c, 1)
+	}
+}
+
+// DisassembleKit breaks kits back down, returning their components to stock
+func DisassembleKit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		changeKitStock(c, -1)
+	}
+}
+
+// changeKitStock assembles (direction 1) or disassembles (direction -1) the requested number of kits
+func changeKitStock(c *gin.Context, direction int) {
+	var kitData kitQuantityRequest
+	if err := c.ShouldBindJSON(&kitData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	kit, ok := findUserItem(c)
+	if !ok {
+		return
+	}
+
+	action, reason := "assemble", models.MovementAssembly
+	if direction < 0 {
+		action, reason = "disassemble", models.MovementDisassembly
+	}
+	reference := fmt.Sprintf("kit:%d", kit.ID)
+
+	var changed []models.Item
+	var shortItem string
+	var outbox events.Outbox
+	DB := db.GetDB()
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var components []models.KitComponent
+		if err := tx.Where("kit_item_id = ?", kit.ID).Find(&components).Error; err != nil {
+			return err
+		}
+		if len(components) == 0 {
+			return errNotAKit
+		}
+
+		// Lock the kit and its components in ID order so concurrent builds can't deadlock
+		ids := []uint{kit.ID}
+		for _, component := range components {
+			ids = append(ids, component.ComponentItemID)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		var items []models.Item
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids).Order("id").Find(&items).Error; err != nil {
+			return err
+		}
+		byID := make(map[uint]*models.Item, len(items))
+		for i := range items {
+			byID[items[i].ID] = &items[i]
+		}
+
+		kitItem := byID[kit.ID]
+		if kitItem == nil {
+			return gorm.ErrRecordNotFound
+		}
+		if direction < 0 {
+			if kitItem.Available() < kitData.Quantity {
+				shortItem = kitItem.Name
+				return errInsufficientStock
+			}
+			if _, err := adjustItemStock(tx, kitItem, -kitData.Quantity, reason, reference); err != nil {
+				return err
+			}
+		}
+
+		for _, component := range components {
+			item := byID[component.ComponentItemID]
+			if item == nil {
+				return gorm.ErrRecordNotFound
+			}
+			delta := component.Quantity * kitData.Quantity * direction
+			if item.Available()+delta < 0 {
+				shortItem = item.Name
+				return errInsufficientStock
+			}
+			if _, err := adjustItemStock(tx, item, delta, reason, reference); err != nil {
+				return err
+			}
+			changed = append(changed, *item)
+		}
+
+		if direction > 0 {
+			if _, err := adjustItemStock(tx, kitItem, kitData.Quantity, reason, reference); err != nil {
+				return err
+			}
+		}
+		kit = *kitItem
+		changed = append(changed, kit)
+		for _, item := range changed {
+			if err := outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionUpdated, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == errNotAKit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Item has no components"})
+		return
+	}
+	if err == errInsufficientStock {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Not enough %s to %s %d kits", shortItem, action, kitData.Quantity)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + action + " kit: " + err.Error()})
+		return
+	}
+	outbox.Broadcast()
+
+	c.JSON(http.StatusOK, gin.H{"item": kit, "quantity": kitData.Quantity, "components": changed[:len(changed)-1]})
+}
+
+// kitBuildable is how many kits the components' unreserved stock is enough for
+func kitBuildable(components []models.KitComponent) int {
+	if len(components) == 0 {
+		return 0
+	}
+	buildable := -1
+	for _, component := range components {
+		if component.Quantity <= 0 {
+			continue
+		}
+		count := component.Component.Available() / component.Quantity
+		if buildable < 0 || count < buildable {
+			buildable = count
+		}
+	}
+	if buildable < 0 {
+		return 0
+	}
+	return buildable
+}
+
+// kitSubtreeIDs returns the given items together with everything they are built from
+func kitSubtreeIDs(DB *gorm.DB, itemIDs []uint) ([]uint, error) {
+	var ids []uint
+	result := DB.Raw(`WITH RECURSIVE subtree AS (
+			SELECT id FROM items WHERE id IN ?
+			UNION
+			SELECT kit_components.component_item_id FROM kit_components JOIN subtree ON kit_components.kit_item_id = subtree.id
+		)
+		SELECT id FROM subtree`, itemIDs).Scan(&ids)
+	return ids, result.Error
+}
+
+// removeKitComponents deletes an item's own bill of materials before the item is deleted.
+// Items that other kits are built from are kept until they are removed from those kits.
+func removeKitComponents(tx *gorm.DB, item models.Item) error {
+	var count int64
+	if err := tx.Model(&models.KitComponent{}).Where("component_item_id = ?", item.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errItemInKit
+	}
+	return tx.Where("kit_item_id = ?", item.ID).Delete(&models.KitComponent{}).Error
+}
@@ -1,14 +1,32 @@
 package routes
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/events"
 	"github.com/sidhant-sriv/inventory-api/middleware"
 	"github.com/sidhant-sriv/inventory-api/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"net/http"
 )
 
+// errLocationCycle is returned when a location would be moved inside itself
+var errLocationCycle = fmt.Errorf("location cannot be moved inside itself")
+
+// errLocationHasItems is returned when deleting a location that still holds items
+var errLocationHasItems = fmt.Errorf("location has linked items")
+
+// errLocationHasSublocations is returned when deleting a location other locations are inside
+var errLocationHasSublocations = fmt.Errorf("location has sublocations")
+
+// locationDeleteConflicts are the errors deleteLocation refuses a location with, and what to tell the user
+var locationDeleteConflicts = map[error]string{
+	errLocationHasItems:        "Cannot delete location with linked items",
+	errLocationHasSublocations: "Cannot delete location with sublocations",
+}
+
 // LocationRoutes sets up the routes for location-related operations
 func LocationRoutes(router *gin.Engine) {
 	// Public route for listing locations
@@ -84,10 +102,22 @@ func CreateLocation() gin.HandlerFunc {
 
 		// Create the location in database
 		DB := db.GetDB()
-		if result := DB.Create(&location); result.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create location: " + result.Error.Error()})
+		if location.ParentID != nil && !userCanUseLocation(DB, userID, *location.ParentID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent location not found"})
+			return
+		}
+		var outbox events.Outbox
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&location).Error; err != nil {
+				return err
+			}
+			return outbox.Publish(tx, location.UserID, events.EntityLocation, location.ID, events.ActionCreated, location)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create location: " + err.Error()})
 			return
 		}
+		outbox.Broadcast()
 
 		c.JSON(http.StatusCreated, gin.H{"location": location})
 	}
@@ -108,8 +138,7 @@ func GetLocation() gin.HandlerFunc {
 
 		// Get the location from the database
 		DB := db.GetDB()
-		// Allow access if location is public (user_id = 0 or NULL) or owned by the current user
-		if result := DB.Where("id = ? AND (user_id = ? OR user_id = 0 OR user_id IS NULL)", locationID, userID).First(&location); result.Error != nil {
+		if result := DB.First(&location, locationID); result.Error != nil {
 			if result.Error == gorm.ErrRecordNotFound {
 				c.JSON(http.StatusNotFound, gin.H{"error": "Location not found or access denied"})
 			} else {
@@ -118,7 +147,18 @@ func GetLocation() gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"location": location})
+		// Allow access if location is public, owned by the current user or shared with them
+		permission, err := locationPermission(DB, userID, location)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check access: " + err.Error()})
+			return
+		}
+		if permission == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Location not found or access denied"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"location": location, "permission": permission})
 	}
 }
 
@@ -153,17 +193,40 @@ func UpdateLocation() gin.HandlerFunc {
 			return
 		}
 
+		if updateData.ParentID != nil && !userCanUseLocation(DB, userID, *updateData.ParentID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent location not found"})
+			return
+		}
+
 		// Update fields (preserving UserID)
 		location.Name = updateData.Name
 		location.Description = updateData.Description
 		location.ImageUrl = updateData.ImageUrl
+		location.ParentID = updateData.ParentID
 		// Don't allow changing the UserID
 
-		// Update the location in the database
-		if result := DB.Save(&location); result.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update location: " + result.Error.Error()})
+		// Update the location in the database. A location can't be moved inside itself or one of
+		// the locations it contains; the check runs with the affected rows locked so two
+		// concurrent moves can't build a cycle between them.
+		var outbox events.Outbox
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := checkLocationMove(tx, userID, location); err != nil {
+				return err
+			}
+			if err := tx.Save(&location).Error; err != nil {
+				return err
+			}
+			return outbox.Publish(tx, location.UserID, events.EntityLocation, location.ID, events.ActionUpdated, location)
+		})
+		if err == errLocationCycle {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A location cannot be moved inside itself or its sublocations"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update location: " + err.Error()})
 			return
 		}
+		outbox.Broadcast()
 
 		c.JSON(http.StatusOK, gin.H{"location": location})
 	}
@@ -193,24 +256,112 @@ func DeleteLocation() gin.HandlerFunc {
 			return
 		}
 
-		// Check if there are any items linked to this location
-		var count int64
-		if result := DB.Model(&models.Item{}).Where("location_id = ?", locationID).Count(&count); result.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check items: " + result.Error.Error()})
+		// Delete the location with its shares and share links from the database
+		var outbox events.Outbox
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			// Published first, while the shares that say who else sees it still exist
+			if err := outbox.Publish(tx, location.UserID, events.EntityLocation, location.ID, events.ActionDeleted, location); err != nil {
+				return err
+			}
+			return deleteLocation(tx, location)
+		})
+		if message, refused := locationDeleteConflicts[err]; refused {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
 			return
 		}
-
-		if count > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete location with linked items"})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete location: " + err.Error()})
 			return
 		}
+		outbox.Broadcast()
 
-		// Delete the location from the database
-		if result := DB.Delete(&location); result.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete location: " + result.Error.Error()})
-			return
+		c.JSON(http.StatusOK, gin.H{"message": "Location deleted successfully"})
+	}
+}
+
+// deleteLocation removes an empty location with its shares and share links. Locations that
+// still hold items or sublocations are refused with one of the errors in locationDeleteConflicts.
+func deleteLocation(tx *gorm.DB, location models.Location) error {
+	var count int64
+	if err := tx.Model(&models.Item{}).Where("location_id = ?", location.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errLocationHasItems
+	}
+	if err := tx.Model(&models.Location{}).Where("parent_id = ?", location.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errLocationHasSublocations
+	}
+
+	if err := tx.Where("location_id = ?", location.ID).Delete(&models.Share{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("location_id = ?", location.ID).Delete(&models.ShareLink{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&location).Error
+}
+
+// checkLocationMove locks a location that is being moved under its new parent and refuses the
+// move with errLocationCycle if the parent is the location itself or inside it
+func checkLocationMove(tx *gorm.DB, userID uint, location models.Location) error {
+	if location.ParentID == nil {
+		return nil
+	}
+	if err := lockLocationMove(tx, location.ID, *location.ParentID); err != nil {
+		return err
+	}
+	subtree, err := locationSubtreeIDs(tx, userID, location.ID)
+	if err != nil {
+		return err
+	}
+	for _, id := range subtree {
+		if id == *location.ParentID {
+			return errLocationCycle
 		}
+	}
+	return nil
+}
 
-		c.JSON(http.StatusOK, gin.H{"message": "Location deleted successfully"})
+// lockLocationMove locks a location that is being moved together with its new parent and every
+// location above that. Two moves that could form a cycle always share one of these rows, so
+// the second waits and then sees the first.
+func lockLocationMove(tx *gorm.DB, locationID uint, parentID uint) error {
+	var ids []uint
+	result := tx.Raw(`WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM locations WHERE id = ?
+			UNION
+			SELECT locations.id, locations.parent_id FROM locations JOIN ancestors ON locations.id = ancestors.parent_id
+		)
+		SELECT id FROM ancestors`, parentID).Scan(&ids)
+	if result.Error != nil {
+		return result.Error
 	}
+	ids = append(ids, locationID)
+
+	var locked []models.Location
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids).Order("id").Find(&locked).Error
+}
+
+// userCanUseLocation reports whether a location is public or owned by the user
+func userCanUseLocation(DB *gorm.DB, userID uint, locationID uint) bool {
+	var count int64
+	DB.Model(&models.Location{}).Where("id = ? AND (user_id = ? OR user_id = 0 OR user_id IS NULL)", locationID, userID).Count(&count)
+	return count > 0
+}
+
+// locationSubtreeIDs returns a location and every location nested inside it that the user can see
+func locationSubtreeIDs(DB *gorm.DB, userID uint, locationID uint) ([]uint, error) {
+	var ids []uint
+	result := DB.Raw(`WITH RECURSIVE subtree AS (
+			SELECT id FROM locations WHERE id = ? AND (user_id = ? OR user_id = 0 OR user_id IS NULL)
+			UNION
+			SELECT locations.id FROM locations JOIN subtree ON locations.parent_id = subtree.id
+			WHERE locations.user_id = ? OR locations.user_id = 0 OR locations.user_id IS NULL
+		)
+		SELECT id FROM subtree`, locationID, userID, userID).Scan(&ids)
+	return ids, result.Error
 }
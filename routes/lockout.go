@@ -0,0 +1,290 @@
+// routes/lockout.go
+package routes
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/mailer"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"github.com/sidhant-sriv/inventory-api/passwords"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Failed-login limits. Failures older than the lockout duration are forgotten.
+const (
+	loginDelayAfter = 3                // failures before each attempt has to wait
+	loginMaxDelay   = 30 * time.Second // longest wait between attempts before the lockout
+)
+
+var (
+	dummyPasswordHashOnce sync.Once
+	dummyPasswordHash     string
+)
+
+// LockoutRoutes sets up the administrator routes for locked accounts
+func LockoutRoutes(router *gin.Engine) {
+	adminRoutes := router.Group("/admin/users")
+	adminRoutes.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	{
+		adminRoutes.GET("/:user_id/lockout", GetUserLockout())
+		adminRoutes.POST("/:user_id/unlock", UnlockUser())
+	}
+}
+
+// GetUserLockout shows an account's recent failed logins and whether it is locked
+func GetUserLockout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		DB := db.GetDB()
+		if result := DB.First(&user, c.Param("user_id")); result.Error != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		var throttle models.LoginThrottle
+		result := DB.Where("key = ?", models.EmailThrottleKey(user.Email)).First(&throttle)
+		if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve lockout: " + result.Error.Error()})
+			return
+		}
+
+		locked := throttle.LockedUntil != nil && time.Now().Before(*throttle.LockedUntil)
+		c.JSON(http.StatusOK, gin.H{
+			"user_id":         user.ID,
+			"locked":          locked,
+			"locked_until":    throttle.LockedUntil,
+			"failures":        throttle.Failures,
+			"last_failure_at": throttle.LastFailureAt,
+		})
+	}
+}
+
+// UnlockUser clears an account's failed logins so the user can sign in straight away
+func UnlockUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		DB := db.GetDB()
+		if result := DB.First(&user, c.Param("user_id")); result.Error != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		if err := clearLoginFailures(DB, user.Email); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user: " + err.Error()})
+			return
+		}
+		fmt.Printf("Admin user ID %d unlocked user ID %d\n", middleware.GetUserID(c), user.ID)
+
+		c.JSON(http.StatusOK, gin.H{"message": "User unlocked"})
+	}
+}
+
+// throttleLimit is a throttle key and the number of failures that locks it
+type throttleLimit struct {
+	key         string
+	maxFailures int
+}
+
+// loginAttempt is a guess at a password or code that has already been counted against its
+// throttles. Counting before the check means concurrent guesses can't all get in ahead of
+// the first recorded failure; a correct guess is refunded afterwards.
+type loginAttempt struct {
+	limits []throttleLimit
+	locked map[string]bool // keys this attempt locked out
+}
+
+// beginLoginAttempt counts a login attempt against the email and the client's IP, or
+// responds with 429 and returns nil if either is locked out or has to wait
+func beginLoginAttempt(c *gin.Context, email string) *loginAttempt {
+	return beginThrottledAttempt(c,
+		throttleLimit{models.EmailThrottleKey(email), loginMaxFailures()},
+		throttleLimit{models.IPThrottleKey(c.ClientIP()), loginIPMaxFailures()},
+	)
+}
+
+// beginThrottledAttempt counts an attempt against each throttle, or responds with 429 and
+// returns nil if any of them is locked out or has to wait
+func beginThrottledAttempt(c *gin.Context, limits ...throttleLimit) *loginAttempt {
+	attempt := &loginAttempt{limits: limits, locked: make(map[string]bool)}
+	retryAfter, err := countAttempt(db.GetDB(), attempt)
+	if err != nil {
+		// Don't lock everyone out because the throttle table is unavailable
+		fmt.Printf("Error counting login attempt: %v\n", err)
+		return attempt
+	}
+	if retryAfter <= 0 {
+		return attempt
+	}
+
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed attempts, please try again later",
+		"retry_after": seconds,
+	})
+	return nil
+}
+
+// countAttempt locks the attempt's throttles and, unless one of them says to wait, adds the
+// attempt to each, starting a new count where the last failure is old enough to be forgotten.
+// It returns how long to wait if the attempt was turned away.
+func countAttempt(DB *gorm.DB, attempt *loginAttempt) (time.Duration, error) {
+	now := time.Now()
+	keys := make([]string, len(attempt.limits))
+	for i, limit := range attempt.limits {
+		keys[i] = limit.key
+		if err := DB.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.LoginThrottle{Key: limit.key, LastFailureAt: now}).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	var retryAfter time.Duration
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// Lock in key order so concurrent attempts can't deadlock
+		var throttles []models.LoginThrottle
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key IN ?", keys).Order("key").Find(&throttles).Error; err != nil {
+			return err
+		}
+
+		for _, throttle := range throttles {
+			wait := time.Duration(0)
+			if throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil) {
+				wait = throttle.LockedUntil.Sub(now)
+			} else if throttle.LastFailureAt.After(now.Add(-loginLockoutDuration())) && throttle.Failures >= loginDelayAfter {
+				wait = throttle.LastFailureAt.Add(loginDelay(throttle.Failures)).Sub(now)
+			}
+			if wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+		if retryAfter > 0 {
+			return nil
+		}
+
+		for _, throttle := range throttles {
+			failures := throttle.Failures + 1
+			if throttle.LastFailureAt.Before(now.Add(-loginLockoutDuration())) {
+				failures = 1
+			}
+			updates := map[string]interface{}{"failures": failures, "last_failure_at": now, "locked_until": nil}
+			if failures >= attempt.maxFailures(throttle.Key) {
+				updates["locked_until"] = now.Add(loginLockoutDuration())
+				attempt.locked[throttle.Key] = true
+			}
+			if err := tx.Model(&throttle).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return retryAfter, err
+}
+
+// maxFailures is the limit for one of the attempt's throttle keys
+func (attempt *loginAttempt) maxFailures(key string) int {
+	for _, limit := range attempt.limits {
+		if limit.key == key {
+			return limit.maxFailures
+		}
+	}
+	return math.MaxInt
+}
+
+// fail reports a wrong guess, which has already been counted. If it locked an existing
+// account its owner is told by email.
+func (attempt *loginAttempt) fail(c *gin.Context, user *models.User) {
+	if attempt.locked[models.IPThrottleKey(c.ClientIP())] {
+		fmt.Printf("Locked out logins from %s after repeated failures\n", c.ClientIP())
+	}
+	if user != nil && attempt.locked[models.EmailThrottleKey(user.Email)] {
+		fmt.Printf("Locked user ID %d after repeated failed logins\n", user.ID)
+		sendEmail(mailer.Message{
+			To:      user.Email,
+			Subject: "Sign-in to your account has been locked",
+			Body: fmt.Sprintf("Hi %s,\n\nThere were %d failed attempts to sign in to your inventory account, so sign-in "+
+				"is locked for %s. If this wasn't you, consider resetting your password once the lock ends, "+
+				"or ask an administrator to unlock your account.\n",
+				user.Name, loginMaxFailures(), loginLockoutDuration()),
+		})
+	}
+}
+
+// refund takes back the count for a guess that turned out to be right (or couldn't be
+// checked), lifting any lockout it caused
+func (attempt *loginAttempt) refund() {
+	DB := db.GetDB()
+	for _, limit := range attempt.limits {
+		if err := DB.Model(&models.LoginThrottle{}).Where("key = ? AND failures > 0", limit.key).Updates(map[string]interface{}{
+			"failures":     gorm.Expr("failures - 1"),
+			"locked_until": gorm.Expr("CASE WHEN failures - 1 < ? THEN NULL ELSE locked_until END", limit.maxFailures),
+		}).Error; err != nil {
+			fmt.Printf("Error refunding login attempt: %v\n", err)
+		}
+	}
+}
+
+// clearLoginFailures forgets an email's failed logins after it signs in or is unlocked.
+// IP counts are left alone so one valid account can't be used to reset them.
+func clearLoginFailures(DB *gorm.DB, email string) error {
+	return DB.Where("key = ?", models.EmailThrottleKey(email)).Delete(&models.LoginThrottle{}).Error
+}
+
+// compareDummyPassword spends as long as a real password check so unknown emails can't be
+// told apart from wrong passwords by timing
+func compareDummyPassword(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = passwords.Hash("not a real password")
+	})
+	passwords.Verify(password, dummyPasswordHash)
+}
+
+// loginDelay is how long to wait after the given number of failures, doubling each time
+func loginDelay(failures int) time.Duration {
+	shift := failures - loginDelayAfter
+	if shift >= 5 {
+		return loginMaxDelay
+	}
+	if delay := time.Second << shift; delay < loginMaxDelay {
+		return delay
+	}
+	return loginMaxDelay
+}
+
+// loginMaxFailures is how many failures lock an account (LOGIN_MAX_FAILURES, default 10)
+func loginMaxFailures() int {
+	return intFromEnv("LOGIN_MAX_FAILURES", 10)
+}
+
+// loginIPMaxFailures is how many failures lock out a client IP (LOGIN_IP_MAX_FAILURES, default 50)
+func loginIPMaxFailures() int {
+	return intFromEnv("LOGIN_IP_MAX_FAILURES", 50)
+}
+
+// loginLockoutDuration is how long a lockout lasts (LOGIN_LOCKOUT_DURATION, default 15m)
+func loginLockoutDuration() time.Duration {
+	if value := os.Getenv("LOGIN_LOCKOUT_DURATION"); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+			return duration
+		}
+	}
+	return 15 * time.Minute
+}
+
+// intFromEnv reads a positive integer from the environment, falling back to a default
+func intFromEnv(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,377 @@
+// routes/lots.go
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/events"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// untrackedLotNumber names the lot that holds stock counted before an item used lots
+const untrackedLotNumber = "untracked"
+
+// errInsufficientStock is returned when a consumption asks for more than is on hand
+var errInsufficientStock = fmt.Errorf("insufficient stock")
+
+// LotRoutes sets up the routes for item lots, FEFO consumption and expiry reports
+func LotRoutes(router *gin.Engine) {
+	lotRoutes := router.Group("/items")
+	lotRoutes.Use(middleware.AuthMiddleware())
+	{
+		lotRoutes.GET("/expiring", GetExpiringLots())
+		lotRoutes.GET("/:item_id/lots", GetItemLots())
+		lotRoutes.POST("/:item_id/lots", CreateLot())
+		lotRoutes.PUT("/:item_id/lots/:lot_id", UpdateLot())
+		lotRoutes.DELETE("/:item_id/lots/:lot_id", DeleteLot())
+		lotRoutes.POST("/:item_id/consume", ConsumeItem())
+	}
+}
+
+// lotRequest is the payload for creating or updating a lot
+type lotRequest struct {
+	LotNumber string     `json:"lot_number"`
+	Quantity  int        `json:"quantity" binding:"min=0"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// GetItemLots lists an item's lots, soonest expiry first
+func GetItemLots() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		var lots []models.Lot
+		DB := db.GetDB()
+		if result := DB.Where("item_id = ?", item.ID).Order("expires_at ASC NULLS LAST, id").Find(&lots); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve lots: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"lots": lots})
+	}
+}
+
+// CreateLot records a new batch of an item and adds its quantity to the item's stock.
+// Stock the item had before its first lot is kept in an "untracked" lot.
+func CreateLot() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var lotData lotRequest
+		if err := c.ShouldBindJSON(&lotData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		lot := models.Lot{
+			ItemID:    item.ID,
+			UserID:    item.UserID,
+			LotNumber: lotData.LotNumber,
+			Quantity:  lotData.Quantity,
+			ExpiresAt: lotData.ExpiresAt,
+		}
+
+		var untracked *models.Lot
+		var outbox events.Outbox
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, item.ID).Error; err != nil {
+				return err
+			}
+			var lotCount int64
+			if err := tx.Model(&models.Lot{}).Where("item_id = ?", item.ID).Count(&lotCount).Error; err != nil {
+				return err
+			}
+			if lotCount == 0 && item.Quantity > 0 {
+				untracked = &models.Lot{
+					ItemID:    item.ID,
+					UserID:    item.UserID,
+					LotNumber: untrackedLotNumber,
+					Quantity:  item.Quantity,
+				}
+				if err := tx.Create(untracked).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Create(&lot).Error; err != nil {
+				return err
+			}
+			if err := syncItemQuantity(tx, &item, models.MovementLot); err != nil {
+				return err
+			}
+			return outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionUpdated, item)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create lot: " + err.Error()})
+			return
+		}
+		outbox.Broadcast()
+
+		response := gin.H{"lot": lot, "item_quantity": item.Quantity}
+		if untracked != nil {
+			response["untracked_lot"] = untracked
+		}
+		c.JSON(http.StatusCreated, response)
+	}
+}
+
+// UpdateLot changes a lot's number, quantity or expiry date. The item's new total must still
+// cover its reservations.
+func UpdateLot() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var lotData lotRequest
+		if err := c.ShouldBindJSON(&lotData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		var lot models.Lot
+		if result := DB.Where("id = ? AND item_id = ?", c.Param("lot_id"), item.ID).First(&lot); result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Lot not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve lot: " + result.Error.Error()})
+			}
+			return
+		}
+
+		lot.LotNumber = lotData.LotNumber
+		lot.Quantity = lotData.Quantity
+		lot.ExpiresAt = lotData.ExpiresAt
+
+		// The item is locked like the reservation paths do, so its new total can't slip below
+		// a reservation made at the same time
+		var outbox events.Outbox
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, item.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Omit(clause.Associations).Save(&lot).Error; err != nil {
+				return err
+			}
+			if err := syncItemQuantity(tx, &item, models.MovementLot); err != nil {
+				return err
+			}
+			if err := checkReservedQuantity(tx, &item); err != nil {
+				return err
+			}
+			return outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionUpdated, item)
+		})
+		if err == errBelowReserved {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Quantity can't be lower than the %d units reserved", item.ReservedQuantity)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update lot: " + err.Error()})
+			return
+		}
+		outbox.Broadcast()
+
+		c.JSON(http.StatusOK, gin.H{"lot": lot, "item_quantity": item.Quantity})
+	}
+}
+
+// DeleteLot removes a lot and its quantity from the item's stock, unless that would leave
+// less than is reserved
+func DeleteLot() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		var lot models.Lot
+		if result := DB.Where("id = ? AND item_id = ?", c.Param("lot_id"), item.ID).First(&lot); result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Lot not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve lot: " + result.Error.Error()})
+			}
+			return
+		}
+
+		var outbox events.Outbox
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, item.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&lot).Error; err != nil {
+				return err
+			}
+			if err := syncItemQuantity(tx, &item, models.MovementLot); err != nil {
+				return err
+			}
+			if err := checkReservedQuantity(tx, &item); err != nil {
+				return err
+			}
+			return outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionUpdated, item)
+		})
+		if err == errBelowReserved {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Quantity can't be lower than the %d units reserved", item.ReservedQuantity)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete lot: " + err.Error()})
+			return
+		}
+		outbox.Broadcast()
+
+		c.JSON(http.StatusOK, gin.H{"message": "Lot deleted successfully", "item_quantity": item.Quantity})
+	}
+}
+
+// ConsumeItem takes stock out of an item first-expired-first-out.
+// Items without lots are decremented directly.
+func ConsumeItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var consumeRequest struct {
+			Quantity int `json:"quantity" binding:"required,min=1"`
+		}
+		if err := c.ShouldBindJSON(&consumeRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		var consumed []lotUsage
+		var outbox events.Outbox
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			// Reserved stock is held for someone else and can't be consumed
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, item.ID).Error; err != nil {
+				return err
+			}
+			if item.Available() < consumeRequest.Quantity {
+				return errInsufficientStock
+			}
+			var err error
+			if consumed, err = adjustItemStock(tx, &item, -consumeRequest.Quantity, models.MovementConsume, ""); err != nil {
+				return err
+			}
+			return outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionUpdated, item)
+		})
+		if err == errInsufficientStock {
+			c.JSON(http.StatusConflict, gin.H{"error": "Not enough available stock to consume " + strconv.Itoa(consumeRequest.Quantity)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to consume item: " + err.Error()})
+			return
+		}
+		outbox.Broadcast()
+
+		c.JSON(http.StatusOK, gin.H{
+			"item_id":       item.ID,
+			"consumed":      consumeRequest.Quantity,
+			"lots":          consumed,
+			"item_quantity": item.Quantity,
+		})
+	}
+}
+
+// GetExpiringLots reports lots with stock that expire within the given window (default 30d).
+// Already expired lots are included so nothing slips through.
+func GetExpiringLots() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		within, err := parseWithin(c.DefaultQuery("within", "30d"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid within parameter. Use days (30d) or a duration (12h)"})
+			return
+		}
+		cutoff := time.Now().Add(within)
+
+		var lots []models.Lot
+		DB := db.GetDB()
+		if result := DB.Preload("Item").
+			Where("user_id = ? AND quantity > 0 AND expires_at IS NOT NULL AND expires_at <= ?", userID, cutoff).
+			Order("expires_at").
+			Find(&lots); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve lots: " + result.Error.Error()})
+			return
+		}
+
+		now := time.Now()
+		report := make([]gin.H, 0, len(lots))
+		for _, lot := range lots {
+			report = append(report, gin.H{
+				"lot_id":     lot.ID,
+				"lot_number": lot.LotNumber,
+				"item_id":    lot.ItemID,
+				"item_name":  lot.Item.Name,
+				"quantity":   lot.Quantity,
+				"expires_at": lot.ExpiresAt,
+				"expired":    lot.ExpiresAt.Before(now),
+				"days_left":  int(lot.ExpiresAt.Sub(now).Hours() / 24),
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"within": c.DefaultQuery("within", "30d"),
+			"cutoff": cutoff,
+			"lots":   report,
+		})
+	}
+}
+
+// syncItemQuantity recomputes an item's quantity as the sum of its lots and records
+// the difference in the stock ledger under the given reason
+func syncItemQuantity(tx *gorm.DB, item *models.Item, reason string) error {
+	var total int64
+	if err := tx.Model(&models.Lot{}).Where("item_id = ?", item.ID).Select("COALESCE(SUM(quantity), 0)").Scan(&total).Error; err != nil {
+		return err
+	}
+	previousQuantity := item.Quantity
+	item.Quantity = int(total)
+	if err := tx.Model(item).Update("quantity", item.Quantity).Error; err != nil {
+		return err
+	}
+	return recordStockChange(tx, *item, previousQuantity, reason, "")
+}
+
+// parseWithin parses a look-ahead window such as "30d" or a Go duration such as "12h"
+func parseWithin(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid day count %q", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return duration, nil
+}
@@ -0,0 +1,375 @@
+// routes/maintenance.go
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/currency"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"github.com/sidhant-sriv/inventory-api/schedule"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaintenanceRoutes sets up the routes for maintenance tasks and the due list
+func MaintenanceRoutes(router *gin.Engine) {
+	itemMaintenanceRoutes := router.Group("/items")
+	itemMaintenanceRoutes.Use(middleware.AuthMiddleware())
+	{
+		itemMaintenanceRoutes.GET("/due", GetDue())
+		itemMaintenanceRoutes.GET("/:item_id/maintenance", GetItemMaintenanceTasks())
+		itemMaintenanceRoutes.POST("/:item_id/maintenance", CreateMaintenanceTask())
+	}
+
+	maintenanceRoutes := router.Group("/maintenance")
+	maintenanceRoutes.Use(middleware.AuthMiddleware())
+	{
+		maintenanceRoutes.GET("/:task_id", GetMaintenanceTask())
+		maintenanceRoutes.PUT("/:task_id", UpdateMaintenanceTask())
+		maintenanceRoutes.DELETE("/:task_id", DeleteMaintenanceTask())
+		maintenanceRoutes.POST("/:task_id/complete", CompleteMaintenanceTask())
+	}
+}
+
+// maintenanceRequest is the payload for creating or updating a maintenance task
+type maintenanceRequest struct {
+	Name         string     `json:"name" binding:"required"`
+	Description  string     `json:"description"`
+	IntervalDays int        `json:"interval_days" binding:"min=0"`
+	Rule         string     `json:"rule"`        // cron-style, e.g. "0 9 1 */3 *"
+	NextDueAt    *time.Time `json:"next_due_at"` // overrides the computed due date
+}
+
+// CreateMaintenanceTask adds a recurring maintenance task to an item
+func CreateMaintenanceTask() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var taskData maintenanceRequest
+		if err := c.ShouldBindJSON(&taskData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		task := models.MaintenanceTask{UserID: item.UserID, ItemID: item.ID}
+		if err := applyMaintenanceRequest(&task, taskData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule: " + err.Error()})
+			return
+		}
+
+		DB := db.GetDB()
+		if result := DB.Omit(clause.Associations).Create(&task); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create maintenance task: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"task": task})
+	}
+}
+
+// GetItemMaintenanceTasks lists an item's maintenance tasks, soonest due first
+func GetItemMaintenanceTasks() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		var tasks []models.MaintenanceTask
+		DB := db.GetDB()
+		if result := DB.Where("item_id = ?", item.ID).Order("next_due_at").Find(&tasks); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve maintenance tasks: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"item_id": item.ID, "tasks": tasks})
+	}
+}
+
+// GetMaintenanceTask retrieves a task along with its completion log, newest first
+func GetMaintenanceTask() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		task, ok := findUserMaintenanceTask(c)
+		if !ok {
+			return
+		}
+
+		var logs []models.MaintenanceLog
+		DB := db.GetDB()
+		if result := DB.Where("task_id = ?", task.ID).Order("completed_at DESC").Find(&logs); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve maintenance log: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"task": task, "logs": logs})
+	}
+}
+
+// UpdateMaintenanceTask changes a task's details or schedule
+func UpdateMaintenanceTask() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var taskData maintenanceRequest
+		if err := c.ShouldBindJSON(&taskData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		task, ok := findUserMaintenanceTask(c)
+		if !ok {
+			return
+		}
+		if err := applyMaintenanceRequest(&task, taskData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule: " + err.Error()})
+			return
+		}
+
+		DB := db.GetDB()
+		if result := DB.Omit(clause.Associations).Save(&task); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update maintenance task: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"task": task})
+	}
+}
+
+// DeleteMaintenanceTask removes a task and its completion log
+func DeleteMaintenanceTask() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		task, ok := findUserMaintenanceTask(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("task_id = ?", task.ID).Delete(&models.MaintenanceLog{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&task).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete maintenance task: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Maintenance task deleted successfully"})
+	}
+}
+
+// CompleteMaintenanceTask logs that a task was done and schedules its next occurrence
+func CompleteMaintenanceTask() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var completeData struct {
+			CompletedAt *time.Time `json:"completed_at"` // defaults to now
+			Notes       string     `json:"notes"`
+			Cost        float64    `json:"cost" binding:"min=0"`
+			Currency    string     `json:"currency"` // defaults to the item's currency
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&completeData); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+				return
+			}
+		}
+
+		task, ok := findUserMaintenanceTask(c)
+		if !ok {
+			return
+		}
+
+		completedAt := time.Now()
+		if completeData.CompletedAt != nil {
+			if completeData.CompletedAt.After(completedAt) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "completed_at cannot be in the future"})
+				return
+			}
+			completedAt = *completeData.CompletedAt
+		}
+		code := itemCurrency(task.Item)
+		if completeData.Currency != "" {
+			code = completeData.Currency
+		}
+		code, err := currency.Normalize(code)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency: " + err.Error()})
+			return
+		}
+
+		nextDue, err := nextMaintenanceDue(task, completedAt)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to schedule next occurrence: " + err.Error()})
+			return
+		}
+
+		entry := models.MaintenanceLog{
+			TaskID:      task.ID,
+			ItemID:      task.ItemID,
+			UserID:      task.UserID,
+			CompletedAt: completedAt,
+			Notes:       completeData.Notes,
+			Cost:        completeData.Cost,
+			Currency:    code,
+		}
+		DB := db.GetDB()
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+			// A back-dated completion never moves the schedule backwards
+			if task.LastCompletedAt != nil && task.LastCompletedAt.After(completedAt) {
+				return nil
+			}
+			task.LastCompletedAt = &completedAt
+			task.NextDueAt = nextDue
+			return tx.Model(&task).Updates(map[string]interface{}{"last_completed_at": completedAt, "next_due_at": nextDue}).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete maintenance task: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"task": task, "log": entry})
+	}
+}
+
+// GetDue lists maintenance that is overdue or due soon and warranties that end soon.
+//
+//	days=<n>  look-ahead window, default 30
+func GetDue() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		days := 30
+		if value := c.Query("days"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a non-negative number"})
+				return
+			}
+			days = parsed
+		}
+		now := time.Now()
+		cutoff := now.AddDate(0, 0, days)
+
+		DB := db.GetDB()
+		var tasks []models.MaintenanceTask
+		if result := DB.Preload("Item").Where("user_id = ? AND next_due_at <= ?", userID, cutoff).Order("next_due_at").Find(&tasks); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve maintenance tasks: " + result.Error.Error()})
+			return
+		}
+
+		var items []models.Item
+		if result := DB.Where("user_id = ? AND warranty_ends_at >= ? AND warranty_ends_at <= ?", userID, now, cutoff).Order("warranty_ends_at").Find(&items); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve warranties: " + result.Error.Error()})
+			return
+		}
+
+		type dueTask struct {
+			models.MaintenanceTask
+			Overdue bool `json:"overdue"`
+		}
+		maintenance := make([]dueTask, 0, len(tasks))
+		for _, task := range tasks {
+			maintenance = append(maintenance, dueTask{MaintenanceTask: task, Overdue: task.NextDueAt.Before(now)})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"until":       cutoff,
+			"maintenance": maintenance,
+			"warranties":  items,
+		})
+	}
+}
+
+// applyMaintenanceRequest copies a task payload onto a task, checking the schedule and
+// working out when the task is next due if the schedule is new or has changed
+func applyMaintenanceRequest(task *models.MaintenanceTask, taskData maintenanceRequest) error {
+	if (taskData.IntervalDays > 0) == (taskData.Rule != "") {
+		return fmt.Errorf("set either interval_days or rule")
+	}
+
+	scheduleChanged := task.ID == 0 || task.IntervalDays != taskData.IntervalDays || task.Rule != taskData.Rule
+	task.Name = taskData.Name
+	task.Description = taskData.Description
+	task.IntervalDays = taskData.IntervalDays
+	task.Rule = taskData.Rule
+
+	if taskData.NextDueAt != nil {
+		if _, err := nextMaintenanceDue(*task, *taskData.NextDueAt); err != nil {
+			return err
+		}
+		task.NextDueAt = *taskData.NextDueAt
+		return nil
+	}
+
+	if !scheduleChanged {
+		return nil
+	}
+	from := time.Now()
+	if task.LastCompletedAt != nil {
+		from = *task.LastCompletedAt
+	}
+	next, err := nextMaintenanceDue(*task, from)
+	if err != nil {
+		return err
+	}
+	task.NextDueAt = next
+	return nil
+}
+
+// nextMaintenanceDue is when a task falls due after the given time
+func nextMaintenanceDue(task models.MaintenanceTask, after time.Time) (time.Time, error) {
+	if task.Rule == "" {
+		return after.AddDate(0, 0, task.IntervalDays), nil
+	}
+	rule, err := schedule.Parse(task.Rule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return rule.Next(after)
+}
+
+// validateWarranty checks that a warranty doesn't end before it starts
+func validateWarranty(item models.Item) error {
+	if item.WarrantyStartsAt != nil && item.WarrantyEndsAt != nil && item.WarrantyEndsAt.Before(*item.WarrantyStartsAt) {
+		return fmt.Errorf("warranty_ends_at must be after warranty_starts_at")
+	}
+	return nil
+}
+
+// findUserMaintenanceTask loads a maintenance task (with its item) if it belongs to the authenticated user.
+// It writes the error response itself and reports whether the handler should continue.
+func findUserMaintenanceTask(c *gin.Context) (models.MaintenanceTask, bool) {
+	var task models.MaintenanceTask
+
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return task, false
+	}
+
+	DB := db.GetDB()
+	if result := DB.Preload("Item").Where("id = ? AND user_id = ?", c.Param("task_id"), userID).First(&task); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Maintenance task not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve maintenance task: " + result.Error.Error()})
+		}
+		return task, false
+	}
+	return task, true
+}
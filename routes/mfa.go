@@ -0,0 +1,489 @@
+// routes/mfa.go
+package routes
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	mfaTokenTTL       = 5 * time.Minute // how long a login has to finish the second step
+	mfaPeriod         = 30              // TOTP step in seconds
+	recoveryCodeCount = 10
+)
+
+// errInvalidMFACode is returned when neither a TOTP code nor a recovery code matches
+var errInvalidMFACode = fmt.Errorf("invalid code")
+
+// MFARoutes sets up the routes for TOTP two-factor authentication
+func MFARoutes(router *gin.Engine) {
+	router.POST("/auth/login/mfa", CompleteMFALogin())
+
+	mfaRoutes := router.Group("/auth/mfa")
+	mfaRoutes.Use(middleware.AuthMiddleware())
+	{
+		mfaRoutes.GET("/", GetMFAStatus())
+		mfaRoutes.POST("/enroll", EnrollMFA())
+		mfaRoutes.POST("/confirm", ConfirmMFA())
+		mfaRoutes.POST("/disable", DisableMFA())
+		mfaRoutes.POST("/recovery-codes", RegenerateRecoveryCodes())
+	}
+
+	adminRoutes := router.Group("/admin/users")
+	adminRoutes.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	{
+		adminRoutes.DELETE("/:user_id/mfa", ResetUserMFA())
+	}
+}
+
+// mfaCodeRequest carries either a current TOTP code or an unused recovery code
+type mfaCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// GetMFAStatus reports whether two-factor authentication is on and how many recovery codes are left
+func GetMFAStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := findCurrentUser(c)
+		if !ok {
+			return
+		}
+
+		var remaining int64
+		DB := db.GetDB()
+		if result := DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&remaining); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count recovery codes: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"enabled":                  user.MFAEnabledAt != nil,
+			"enabled_at":               user.MFAEnabledAt,
+			"recovery_codes_remaining": remaining,
+		})
+	}
+}
+
+// EnrollMFA generates a new TOTP secret for the user to add to an authenticator app.
+// Nothing changes at login until the secret is confirmed with a code.
+func EnrollMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := findCurrentUser(c)
+		if !ok {
+			return
+		}
+		if user.MFAEnabledAt != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+
+		issuer := os.Getenv("MFA_ISSUER")
+		if issuer == "" {
+			issuer = "Inventory API"
+		}
+		key, err := totp.Generate(totp.GenerateOpts{Issuer: issuer, AccountName: user.Email, Period: mfaPeriod})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret: " + err.Error()})
+			return
+		}
+
+		DB := db.GetDB()
+		if result := DB.Model(&user).Update("mfa_pending_secret", key.Secret()); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save secret: " + result.Error.Error()})
+			return
+		}
+
+		// A QR code of the provisioning URI for apps that scan one
+		qrCode := ""
+		if image, err := key.Image(256, 256); err == nil {
+			var buffer bytes.Buffer
+			if err := png.Encode(&buffer, image); err == nil {
+				qrCode = "data:image/png;base64," + base64.StdEncoding.EncodeToString(buffer.Bytes())
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"secret":           key.Secret(),
+			"provisioning_uri": key.URL(),
+			"qr_code":          qrCode,
+		})
+	}
+}
+
+// ConfirmMFA turns on two-factor authentication once the user proves their app produces
+// valid codes, and returns a set of recovery codes that are only shown this once
+func ConfirmMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var confirmRequest struct {
+			Code string `json:"code" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&confirmRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		user, ok := findCurrentUser(c)
+		if !ok {
+			return
+		}
+		if user.MFAEnabledAt != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+		if user.MFAPendingSecret == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Start enrolment first"})
+			return
+		}
+		counter, ok := matchTOTP(user.MFAPendingSecret, confirmRequest.Code, 0)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+			return
+		}
+
+		var codes []string
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Updates(map[string]interface{}{
+				"mfa_secret":         user.MFAPendingSecret,
+				"mfa_pending_secret": "",
+				"mfa_enabled_at":     time.Now(),
+				"mfa_last_counter":   counter,
+			}).Error; err != nil {
+				return err
+			}
+			var err error
+			codes, err = replaceRecoveryCodes(tx, user.ID)
+			return err
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled", "recovery_codes": codes})
+	}
+}
+
+// DisableMFA turns two-factor authentication off; it needs a current code or a recovery code
+func DisableMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var codeRequest mfaCodeRequest
+		if err := c.ShouldBindJSON(&codeRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		user, ok := findCurrentUser(c)
+		if !ok {
+			return
+		}
+		if user.MFAEnabledAt == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+			return
+		}
+
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, user.ID).Error; err != nil {
+				return err
+			}
+			if err := verifyMFA(tx, &user, codeRequest); err != nil {
+				return err
+			}
+			return clearMFA(tx, user.ID)
+		})
+		if err == errInvalidMFACode {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+	}
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes; it needs a current TOTP code
+func RegenerateRecoveryCodes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var codeRequest struct {
+			Code string `json:"code" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&codeRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		user, ok := findCurrentUser(c)
+		if !ok {
+			return
+		}
+		if user.MFAEnabledAt == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+			return
+		}
+
+		var codes []string
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, user.ID).Error; err != nil {
+				return err
+			}
+			if err := verifyMFA(tx, &user, mfaCodeRequest{Code: codeRequest.Code}); err != nil {
+				return err
+			}
+			var err error
+			codes, err = replaceRecoveryCodes(tx, user.ID)
+			return err
+		})
+		if err == errInvalidMFACode {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate recovery codes: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+	}
+}
+
+// CompleteMFALogin is the second login step: it exchanges the MFA challenge token from
+// /auth/login and a TOTP or recovery code for the access and refresh tokens
+func CompleteMFALogin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var loginRequest struct {
+			MFAToken     string `json:"mfa_token" binding:"required"`
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+		if err := c.ShouldBindJSON(&loginRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		userID, err := parseMFAToken(loginRequest.MFAToken)
+		if err != nil {
+			fmt.Printf("Invalid MFA token received: %v\n", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+			return
+		}
+
+		// Guessing codes counts towards the same lockout as guessing passwords
+		var user models.User
+		DB := db.GetDB()
+		if result := DB.First(&user, userID); result.Error != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+			return
+		}
+		attempt := beginLoginAttempt(c, user.Email)
+		if attempt == nil {
+			return
+		}
+
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+				return err
+			}
+			if user.MFAEnabledAt == nil {
+				return errInvalidMFACode
+			}
+			return verifyMFA(tx, &user, mfaCodeRequest{Code: loginRequest.Code, RecoveryCode: loginRequest.RecoveryCode})
+		})
+		if err == errInvalidMFACode || err == gorm.ErrRecordNotFound {
+			attempt.fail(c, &user)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+			return
+		}
+		attempt.refund()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error during login"})
+			return
+		}
+
+		respondWithTokens(c, user)
+	}
+}
+
+// ResetUserMFA lets an administrator turn off two-factor authentication for a user who
+// has lost both their authenticator and their recovery codes
+func ResetUserMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		DB := db.GetDB()
+		if result := DB.First(&user, c.Param("user_id")); result.Error != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		if err := DB.Transaction(func(tx *gorm.DB) error { return clearMFA(tx, user.ID) }); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset two-factor authentication: " + err.Error()})
+			return
+		}
+		fmt.Printf("Admin user ID %d reset two-factor authentication for user ID %d\n", middleware.GetUserID(c), user.ID)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+	}
+}
+
+// verifyMFA checks a TOTP code (rejecting replays of an already used one) or consumes a
+// recovery code. The user row should be locked by the caller when replays matter.
+func verifyMFA(tx *gorm.DB, user *models.User, codeRequest mfaCodeRequest) error {
+	if code := strings.TrimSpace(codeRequest.Code); code != "" {
+		counter, ok := matchTOTP(user.MFASecret, code, user.MFALastCounter)
+		if !ok {
+			return errInvalidMFACode
+		}
+		user.MFALastCounter = counter
+		return tx.Model(user).Update("mfa_last_counter", counter).Error
+	}
+
+	if code := normalizeRecoveryCode(codeRequest.RecoveryCode); code != "" {
+		result := tx.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashUserToken(code)).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidMFACode
+		}
+		return nil
+	}
+	return errInvalidMFACode
+}
+
+// matchTOTP checks a code against the current time step and one step either side to allow
+// for clock drift, only accepting steps after lastCounter. It returns the matching step.
+func matchTOTP(secret string, code string, lastCounter uint64) (uint64, bool) {
+	if secret == "" || code == "" {
+		return 0, false
+	}
+	current := uint64(time.Now().Unix()) / mfaPeriod
+	for _, counter := range []uint64{current - 1, current, current + 1} {
+		if counter > lastCounter && hotp.Validate(code, counter, secret) {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// replaceRecoveryCodes discards a user's recovery codes and returns a fresh set
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		// Ten hex characters shown as xxxxx-xxxxx
+		code := fmt.Sprintf("%x", raw)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		records = append(records, models.RecoveryCode{UserID: userID, CodeHash: hashUserToken(code)})
+	}
+	return codes, tx.Create(&records).Error
+}
+
+// normalizeRecoveryCode accepts recovery codes with or without the dash and in any case
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// clearMFA removes a user's TOTP secret and recovery codes
+func clearMFA(tx *gorm.DB, userID uint) error {
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"mfa_secret":         "",
+		"mfa_pending_secret": "",
+		"mfa_enabled_at":     nil,
+		"mfa_last_counter":   0,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
+
+// generateMFAToken creates the short-lived challenge token returned by Login when a second factor is needed
+func generateMFAToken(userID uint) (string, error) {
+	jwtSecret := os.Getenv("JWT_SECRET_KEY")
+	if jwtSecret == "" {
+		return "", fmt.Errorf("JWT secret key not configured")
+	}
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"exp":     time.Now().Add(mfaTokenTTL).Unix(),
+		"iat":     time.Now().Unix(),
+		"type":    "mfa",
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(jwtSecret))
+}
+
+// parseMFAToken validates an MFA challenge token and returns the user it was issued for
+func parseMFAToken(tokenString string) (uint, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(os.Getenv("JWT_SECRET_KEY")), nil
+	})
+	if err != nil || !token.Valid {
+		return 0, fmt.Errorf("invalid token: %v", err)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["type"] != "mfa" {
+		return 0, fmt.Errorf("not an MFA token")
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("missing user ID")
+	}
+	return uint(userID), nil
+}
+
+// findCurrentUser loads the authenticated user.
+// It writes the error response itself and reports whether the handler should continue.
+func findCurrentUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return user, false
+	}
+
+	DB := db.GetDB()
+	if result := DB.First(&user, userID); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user: " + result.Error.Error()})
+		}
+		return user, false
+	}
+	return user, true
+}
@@ -0,0 +1,353 @@
+// routes/oidc.go
+package routes
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"github.com/sidhant-sriv/inventory-api/passwords"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// oidcStateTTL is how long a user has to finish signing in at the provider
+const oidcStateTTL = 10 * time.Minute
+
+// oidcStateCookie (suffixed with the provider name) ties a callback to the browser that
+// started the login, so an attacker can't sign a victim into the attacker's account by
+// sending them a callback link
+const oidcStateCookie = "oidc_state_"
+
+// errOIDCNotLinked is returned when an identity has no account and provisioning is off
+var errOIDCNotLinked = fmt.Errorf("no account for this identity")
+
+// oidcProvider is one configured OpenID Connect provider. Discovery happens on first use.
+type oidcProvider struct {
+	Name          string
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	AutoProvision bool
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+var (
+	oidcProvidersOnce sync.Once
+	oidcProviders     map[string]*oidcProvider
+)
+
+// OIDCRoutes sets up single sign-on through OpenID Connect providers
+func OIDCRoutes(router *gin.Engine) {
+	oidcRoutes := router.Group("/auth/oidc")
+	{
+		oidcRoutes.GET("/providers", GetOIDCProviders())
+		oidcRoutes.GET("/:provider/login", StartOIDCLogin())
+		oidcRoutes.GET("/:provider/callback", OIDCCallback())
+	}
+}
+
+// GetOIDCProviders lists the providers users can sign in with
+func GetOIDCProviders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		names := []string{}
+		for name := range configuredOIDCProviders() {
+			names = append(names, name)
+		}
+		c.JSON(http.StatusOK, gin.H{"providers": names})
+	}
+}
+
+// StartOIDCLogin redirects to the provider's authorization endpoint using the authorization
+// code flow with PKCE. Pass redirect=false to get the URL as JSON instead; either way the
+// response sets the state cookie the callback checks.
+func StartOIDCLogin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, ok := configuredOIDCProviders()[c.Param("provider")]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown provider"})
+			return
+		}
+		config, _, err := provider.config(c)
+		if err != nil {
+			fmt.Printf("OIDC discovery failed for %s: %v\n", provider.Name, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+			return
+		}
+
+		state := models.OIDCLoginState{
+			State:        randomURLToken(),
+			Provider:     provider.Name,
+			CodeVerifier: oauth2.GenerateVerifier(),
+			Nonce:        randomURLToken(),
+			ExpiresAt:    time.Now().Add(oidcStateTTL),
+		}
+		DB := db.GetDB()
+		// Clear out abandoned logins while we're here
+		DB.Where("expires_at < ?", time.Now()).Delete(&models.OIDCLoginState{})
+		if result := DB.Create(&state); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login: " + result.Error.Error()})
+			return
+		}
+
+		setOIDCStateCookie(c, provider, state.State, int(oidcStateTTL.Seconds()))
+		authURL := config.AuthCodeURL(state.State, oauth2.S256ChallengeOption(state.CodeVerifier), oidc.Nonce(state.Nonce))
+		if c.Query("redirect") == "false" {
+			c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+			return
+		}
+		c.Redirect(http.StatusFound, authURL)
+	}
+}
+
+// OIDCCallback completes the login: it exchanges the code, verifies the ID token, finds or
+// provisions the user and issues the usual access and refresh tokens
+func OIDCCallback() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if errorCode := c.Query("error"); errorCode != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Login was not completed: " + errorCode})
+			return
+		}
+		code, stateValue := c.Query("code"), c.Query("state")
+		if code == "" || stateValue == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "code and state are required"})
+			return
+		}
+
+		provider, ok := configuredOIDCProviders()[c.Param("provider")]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown provider"})
+			return
+		}
+
+		// The callback must come back to the browser that started the login
+		cookieState, _ := c.Cookie(oidcStateCookie + provider.Name)
+		setOIDCStateCookie(c, provider, "", -1)
+		if subtle.ConstantTimeCompare([]byte(cookieState), []byte(stateValue)) != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Login session is invalid or has expired, please start again"})
+			return
+		}
+
+		// The state is single-use: delete it as we read it
+		var state models.OIDCLoginState
+		DB := db.GetDB()
+		result := DB.Clauses(clause.Returning{}).Where("state = ? AND provider = ?", stateValue, provider.Name).Delete(&state)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login state: " + result.Error.Error()})
+			return
+		}
+		if result.RowsAffected == 0 || time.Now().After(state.ExpiresAt) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Login session is invalid or has expired, please start again"})
+			return
+		}
+
+		config, verifier, err := provider.config(c)
+		if err != nil {
+			fmt.Printf("OIDC discovery failed for %s: %v\n", provider.Name, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+			return
+		}
+		token, err := config.Exchange(c.Request.Context(), code, oauth2.VerifierOption(state.CodeVerifier))
+		if err != nil {
+			fmt.Printf("OIDC code exchange failed for %s: %v\n", provider.Name, err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to complete login with the identity provider"})
+			return
+		}
+		rawIDToken, ok := token.Extra("id_token").(string)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Identity provider did not return an ID token"})
+			return
+		}
+		idToken, err := verifier.Verify(c.Request.Context(), rawIDToken)
+		if err != nil || idToken.Nonce != state.Nonce {
+			fmt.Printf("OIDC ID token rejected for %s: %v\n", provider.Name, err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ID token"})
+			return
+		}
+
+		var claims struct {
+			Email         string `json:"email"`
+			EmailVerified bool   `json:"email_verified"`
+			Name          string `json:"name"`
+		}
+		if err := idToken.Claims(&claims); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ID token claims"})
+			return
+		}
+
+		user, err := findOrProvisionOIDCUser(DB, provider, idToken.Subject, claims.Email, claims.EmailVerified, claims.Name)
+		if err == errOIDCNotLinked {
+			c.JSON(http.StatusForbidden, gin.H{"error": "No account is linked to this identity"})
+			return
+		}
+		if err != nil {
+			fmt.Printf("Error linking OIDC identity for %s: %v\n", provider.Name, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+			return
+		}
+
+		respondWithLogin(c, user)
+	}
+}
+
+// findOrProvisionOIDCUser resolves a provider identity to a user: an existing link first, then
+// an account with the same verified email (which gets linked), then a newly provisioned account.
+// Accounts whose own email was never verified are not linked.
+func findOrProvisionOIDCUser(DB *gorm.DB, provider *oidcProvider, subject string, email string, emailVerified bool, name string) (models.User, error) {
+	var user models.User
+	now := time.Now()
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var identity models.ExternalIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider.Name, subject).First(&identity).Error
+		if err == nil {
+			if err := tx.First(&user, identity.UserID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&identity).Updates(map[string]interface{}{"email": email, "last_login_at": now}).Error
+		}
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		// Only an address the provider has verified can be trusted to identify an account
+		if email == "" || !emailVerified {
+			return errOIDCNotLinked
+		}
+		err = tx.Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+		if err == gorm.ErrRecordNotFound {
+			if !provider.AutoProvision {
+				return errOIDCNotLinked
+			}
+			if user, err = provisionOIDCUser(tx, email, name); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		} else if user.EmailVerifiedAt == nil {
+			// Whoever registered an unverified account may not own the address; linking it
+			// would leave their password, sessions and API keys working on the owner's account
+			return errOIDCNotLinked
+		}
+
+		identity = models.ExternalIdentity{UserID: user.ID, Provider: provider.Name, Subject: subject, Email: email, LastLoginAt: &now}
+		return tx.Create(&identity).Error
+	})
+	return user, err
+}
+
+// provisionOIDCUser creates an account for a first-time single sign-on user. It gets a random
+// password nobody knows, so it can only be used through SSO until the user resets it.
+func provisionOIDCUser(tx *gorm.DB, email string, name string) (models.User, error) {
+	if name == "" {
+		name = strings.Split(email, "@")[0]
+	}
+	hashedPassword, err := passwords.Hash(randomURLToken())
+	if err != nil {
+		return models.User{}, err
+	}
+	now := time.Now()
+	user := models.User{
+		Name:            name,
+		Email:           email,
+		Password:        hashedPassword,
+		Role:            models.RoleUser,
+		EmailVerifiedAt: &now,
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return user, err
+	}
+	if err := claimInvitations(tx, user.ID, user.Email); err != nil {
+		return user, err
+	}
+	fmt.Printf("Provisioned user ID %d from single sign-on\n", user.ID)
+	return user, nil
+}
+
+// setOIDCStateCookie sets (or with a negative maxAge clears) the provider's state cookie.
+// SameSite=Lax still sends it on the top-level redirect back from the provider.
+func setOIDCStateCookie(c *gin.Context, provider *oidcProvider, value string, maxAge int) {
+	secure := c.Request.TLS != nil || strings.HasPrefix(provider.RedirectURL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie+provider.Name, value, maxAge, "/", "", secure, true)
+}
+
+// config returns the OAuth2 configuration and ID token verifier, discovering the provider's
+// endpoints on first use. A failed discovery is retried on the next request.
+func (p *oidcProvider) config(c *gin.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.provider == nil {
+		provider, err := oidc.NewProvider(c.Request.Context(), p.Issuer)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.provider = provider
+	}
+
+	config := &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		RedirectURL:  p.RedirectURL,
+		Endpoint:     p.provider.Endpoint(),
+		Scopes:       p.Scopes,
+	}
+	return config, p.provider.Verifier(&oidc.Config{ClientID: p.ClientID}), nil
+}
+
+// configuredOIDCProviders reads the providers named in OIDC_PROVIDERS (comma-separated).
+// Each one is configured with OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL
+// and optionally _SCOPES (space-separated) and _AUTO_PROVISION=false.
+func configuredOIDCProviders() map[string]*oidcProvider {
+	oidcProvidersOnce.Do(func() {
+		oidcProviders = make(map[string]*oidcProvider)
+		for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+			provider := &oidcProvider{
+				Name:          name,
+				Issuer:        os.Getenv(prefix + "ISSUER"),
+				ClientID:      os.Getenv(prefix + "CLIENT_ID"),
+				ClientSecret:  os.Getenv(prefix + "CLIENT_SECRET"),
+				RedirectURL:   os.Getenv(prefix + "REDIRECT_URL"),
+				Scopes:        []string{oidc.ScopeOpenID, "email", "profile"},
+				AutoProvision: os.Getenv(prefix+"AUTO_PROVISION") != "false",
+			}
+			if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+				provider.Scopes = strings.Fields(scopes)
+			}
+			if provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+				fmt.Printf("Skipping OIDC provider %s: issuer, client ID and redirect URL are required\n", name)
+				continue
+			}
+			oidcProviders[name] = provider
+		}
+	})
+	return oidcProviders
+}
+
+// randomURLToken returns 32 random bytes encoded for use in URLs
+func randomURLToken() string {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		panic(err) // crypto/rand never fails on supported platforms
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
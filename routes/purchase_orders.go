@@ -0,0 +1,538 @@
+// routes/purchase_orders.go
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/currency"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/events"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errItemOnOrder is returned when deleting an item on a purchase order that is still open
+var errItemOnOrder = fmt.Errorf("item is on an open purchase order")
+
+// purchaseOrderTransitions lists the statuses an order can move to by hand.
+// Receiving moves orders to partially_received and received.
+var purchaseOrderTransitions = map[string][]string{
+	models.PurchaseOrderDraft:             {models.PurchaseOrderOrdered, models.PurchaseOrderCancelled},
+	models.PurchaseOrderOrdered:           {models.PurchaseOrderCancelled},
+	models.PurchaseOrderPartiallyReceived: {models.PurchaseOrderCancelled},
+}
+
+// PurchaseOrderRoutes sets up the routes for purchase orders and receiving stock
+func PurchaseOrderRoutes(router *gin.Engine) {
+	orderRoutes := router.Group("/purchase-orders")
+	orderRoutes.Use(middleware.AuthMiddleware())
+	{
+		orderRoutes.POST("/", CreatePurchaseOrder())
+		orderRoutes.GET("/", GetPurchaseOrders())
+		orderRoutes.GET("/:order_id", GetPurchaseOrder())
+		orderRoutes.PUT("/:order_id", UpdatePurchaseOrder())
+		orderRoutes.DELETE("/:order_id", DeletePurchaseOrder())
+		orderRoutes.POST("/:order_id/order", TransitionPurchaseOrder(models.PurchaseOrderOrdered))
+		orderRoutes.POST("/:order_id/cancel", TransitionPurchaseOrder(models.PurchaseOrderCancelled))
+		orderRoutes.POST("/:order_id/receive", ReceivePurchaseOrder())
+	}
+}
+
+// purchaseOrderRequest is the payload for creating or updating a draft purchase order
+type purchaseOrderRequest struct {
+	SupplierID uint       `json:"supplier_id" binding:"required"`
+	Number     string     `json:"number"`
+	Currency   string     `json:"currency"`
+	Notes      string     `json:"notes"`
+	ExpectedAt *time.Time `json:"expected_at"`
+	Lines      []struct {
+		ItemID   uint    `json:"item_id" binding:"required"`
+		Quantity int     `json:"quantity" binding:"required,min=1"`
+		UnitCost float64 `json:"unit_cost" binding:"min=0"`
+	} `json:"lines" binding:"dive"`
+}
+
+// CreatePurchaseOrder drafts a purchase order with a supplier
+func CreatePurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var orderData purchaseOrderRequest
+		if err := c.ShouldBindJSON(&orderData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		order := models.PurchaseOrder{UserID: userID, Status: models.PurchaseOrderDraft}
+		if !applyPurchaseOrderRequest(c, &order, orderData) {
+			return
+		}
+
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit(clause.Associations).Create(&order).Error; err != nil {
+				return err
+			}
+			for i := range order.Lines {
+				order.Lines[i].PurchaseOrderID = order.ID
+			}
+			if len(order.Lines) > 0 {
+				if err := tx.Omit("Item").Create(&order.Lines).Error; err != nil {
+					return err
+				}
+			}
+			if order.Number == "" {
+				order.Number = fmt.Sprintf("PO-%05d", order.ID)
+				return tx.Model(&order).Update("number", order.Number).Error
+			}
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create purchase order: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"purchase_order": order})
+	}
+}
+
+// GetPurchaseOrders lists the user's purchase orders, optionally by status or supplier
+func GetPurchaseOrders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		DB := db.GetDB()
+		query := DB.Preload("Supplier").Preload("Lines").Where("user_id = ?", userID)
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if supplierID := c.Query("supplier_id"); supplierID != "" {
+			query = query.Where("supplier_id = ?", supplierID)
+		}
+
+		var orders []models.PurchaseOrder
+		if result := query.Order("created_at DESC").Find(&orders); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve purchase orders: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"purchase_orders": orders})
+	}
+}
+
+// GetPurchaseOrder retrieves a purchase order with its supplier and lines
+func GetPurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		order, ok := findUserPurchaseOrder(c)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"purchase_order": order})
+	}
+}
+
+// UpdatePurchaseOrder replaces a draft order's supplier, details and lines
+func UpdatePurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var orderData purchaseOrderRequest
+		if err := c.ShouldBindJSON(&orderData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		order, ok := findUserPurchaseOrder(c)
+		if !ok {
+			return
+		}
+		if order.Status != models.PurchaseOrderDraft {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only draft purchase orders can be edited"})
+			return
+		}
+		if !applyPurchaseOrderRequest(c, &order, orderData) {
+			return
+		}
+		if order.Number == "" {
+			order.Number = fmt.Sprintf("PO-%05d", order.ID)
+		}
+
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("purchase_order_id = ?", order.ID).Delete(&models.PurchaseOrderLine{}).Error; err != nil {
+				return err
+			}
+			for i := range order.Lines {
+				order.Lines[i].PurchaseOrderID = order.ID
+			}
+			if len(order.Lines) > 0 {
+				if err := tx.Omit("Item").Create(&order.Lines).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Omit(clause.Associations).Save(&order).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update purchase order: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"purchase_order": order})
+	}
+}
+
+// DeletePurchaseOrder removes a draft purchase order
+func DeletePurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		order, ok := findUserPurchaseOrder(c)
+		if !ok {
+			return
+		}
+		if order.Status != models.PurchaseOrderDraft {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only draft purchase orders can be deleted; cancel it instead"})
+			return
+		}
+
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("purchase_order_id = ?", order.ID).Delete(&models.PurchaseOrderLine{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&order).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete purchase order: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Purchase order deleted successfully"})
+	}
+}
+
+// TransitionPurchaseOrder moves an order to the given status if the transition is allowed
+func TransitionPurchaseOrder(status string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		order, ok := findUserPurchaseOrder(c)
+		if !ok {
+			return
+		}
+
+		allowed := false
+		for _, next := range purchaseOrderTransitions[order.Status] {
+			if next == status {
+				allowed = true
+			}
+		}
+		if !allowed {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Cannot move a %s purchase order to %s", order.Status, status)})
+			return
+		}
+		if status == models.PurchaseOrderOrdered && len(order.Lines) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Purchase order has no lines"})
+			return
+		}
+
+		updates := map[string]interface{}{"status": status}
+		if status == models.PurchaseOrderOrdered {
+			now := time.Now()
+			order.OrderedAt = &now
+			updates["ordered_at"] = now
+		}
+		order.Status = status
+
+		DB := db.GetDB()
+		if result := DB.Model(&order).Updates(updates); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update purchase order: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"purchase_order": order})
+	}
+}
+
+// ReceivePurchaseOrder books delivered stock. Each received line adds a purchase movement at
+// the line's cost (converted to the item's currency), raises the item's quantity and updates
+// its purchase price. Without lines, everything outstanding is received. Stock goes to each
+// item's own location; location_id only places items that have none yet, and is refused for
+// items kept somewhere else.
+func ReceivePurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var receiveData struct {
+			LocationID uint       `json:"location_id"`
+			ReceivedAt *time.Time `json:"received_at"`
+			Lines      []struct {
+				LineID    uint       `json:"line_id" binding:"required"`
+				Quantity  int        `json:"quantity" binding:"required,min=1"`
+				LotNumber string     `json:"lot_number"`
+				ExpiresAt *time.Time `json:"expires_at"`
+			} `json:"lines" binding:"dive"`
+		}
+		if err := c.ShouldBindJSON(&receiveData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		order, ok := findUserPurchaseOrder(c)
+		if !ok {
+			return
+		}
+		if order.Status != models.PurchaseOrderOrdered && order.Status != models.PurchaseOrderPartiallyReceived {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only ordered purchase orders can be received"})
+			return
+		}
+
+		DB := db.GetDB()
+		if receiveData.LocationID != 0 && !userCanUseLocation(DB, order.UserID, receiveData.LocationID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Location not found"})
+			return
+		}
+
+		receivedAt := time.Now()
+		if receiveData.ReceivedAt != nil {
+			receivedAt = *receiveData.ReceivedAt
+		}
+
+		// Work out how much of each line arrives
+		type delivery struct {
+			quantity  int
+			lotNumber string
+			expiresAt *time.Time
+		}
+		deliveries := make(map[uint]delivery)
+		if len(receiveData.Lines) == 0 {
+			for _, line := range order.Lines {
+				if remaining := line.Quantity - line.ReceivedQuantity; remaining > 0 {
+					deliveries[line.ID] = delivery{quantity: remaining}
+				}
+			}
+		}
+		for _, line := range receiveData.Lines {
+			existing := deliveries[line.LineID]
+			deliveries[line.LineID] = delivery{quantity: existing.quantity + line.Quantity, lotNumber: line.LotNumber, expiresAt: line.ExpiresAt}
+		}
+		if len(deliveries) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing left to receive"})
+			return
+		}
+
+		var outbox events.Outbox
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			// Lock the order so concurrent deliveries can't over-receive a line
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, order.ID).Error; err != nil {
+				return err
+			}
+			var lines []models.PurchaseOrderLine
+			if err := tx.Where("purchase_order_id = ?", order.ID).Order("id").Find(&lines).Error; err != nil {
+				return err
+			}
+
+			lineIDs := make(map[uint]bool)
+			for _, line := range lines {
+				lineIDs[line.ID] = true
+			}
+			for lineID := range deliveries {
+				if !lineIDs[lineID] {
+					return fmt.Errorf("line %d is not on this purchase order", lineID)
+				}
+			}
+
+			complete := true
+			for i := range lines {
+				line := &lines[i]
+				arriving, ok := deliveries[line.ID]
+				if ok {
+					if line.ReceivedQuantity+arriving.quantity > line.Quantity {
+						return fmt.Errorf("line %d: receiving %d would exceed the %d ordered", line.ID, arriving.quantity, line.Quantity-line.ReceivedQuantity)
+					}
+
+					item, err := receiveItemStock(tx, order, *line, arriving.quantity, receiveData.LocationID, receivedAt, arriving.lotNumber, arriving.expiresAt)
+					if err != nil {
+						return err
+					}
+					if err := outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionUpdated, item); err != nil {
+						return err
+					}
+
+					line.ReceivedQuantity += arriving.quantity
+					if err := tx.Model(line).Update("received_quantity", line.ReceivedQuantity).Error; err != nil {
+						return err
+					}
+				}
+				if line.ReceivedQuantity < line.Quantity {
+					complete = false
+				}
+			}
+
+			updates := map[string]interface{}{"status": models.PurchaseOrderPartiallyReceived}
+			if complete {
+				updates["status"] = models.PurchaseOrderReceived
+				updates["received_at"] = receivedAt
+			}
+			order.Lines = lines
+			return tx.Model(&order).Updates(updates).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to receive purchase order: " + err.Error()})
+			return
+		}
+		outbox.Broadcast()
+
+		c.JSON(http.StatusOK, gin.H{"purchase_order": order})
+	}
+}
+
+// receiveItemStock adds a delivered quantity to an item and records the purchase movement
+func receiveItemStock(tx *gorm.DB, order models.PurchaseOrder, line models.PurchaseOrderLine, quantity int, locationID uint, receivedAt time.Time, lotNumber string, expiresAt *time.Time) (models.Item, error) {
+	var item models.Item
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", line.ItemID, order.UserID).First(&item).Error; err != nil {
+		return item, fmt.Errorf("item %d: %v", line.ItemID, err)
+	}
+
+	// An item lives in one location, so a delivery can't quietly leave its stock somewhere else
+	if locationID != 0 && item.LocationID != 0 && item.LocationID != locationID {
+		return item, fmt.Errorf("item %d is kept in location %d, not %d; receive it without location_id or move it first", item.ID, item.LocationID, locationID)
+	}
+
+	// The item's cost is kept in its own currency
+	unitCost, err := currency.Convert(tx, line.UnitCost, order.Currency, itemCurrency(item), receivedAt)
+	if err != nil {
+		return item, err
+	}
+
+	// Lot-tracked items (or deliveries with lot details) get a new lot so lot totals stay in step
+	var lotCount int64
+	if err := tx.Model(&models.Lot{}).Where("item_id = ?", item.ID).Count(&lotCount).Error; err != nil {
+		return item, err
+	}
+	if lotCount > 0 || lotNumber != "" || expiresAt != nil {
+		if lotCount == 0 && item.Quantity > 0 {
+			// Keep the stock already on hand as its own lot
+			opening := models.Lot{ItemID: item.ID, UserID: item.UserID, LotNumber: untrackedLotNumber, Quantity: item.Quantity}
+			if err := tx.Create(&opening).Error; err != nil {
+				return item, err
+			}
+		}
+		lot := models.Lot{ItemID: item.ID, UserID: item.UserID, LotNumber: lotNumber, Quantity: quantity, ExpiresAt: expiresAt}
+		if err := tx.Create(&lot).Error; err != nil {
+			return item, err
+		}
+	}
+
+	item.Quantity += quantity
+	item.PurchasePrice = unitCost
+	item.Currency = itemCurrency(item)
+	updates := map[string]interface{}{"quantity": item.Quantity, "purchase_price": item.PurchasePrice, "currency": item.Currency}
+	if item.LocationID == 0 && locationID != 0 {
+		item.LocationID = locationID
+		updates["location_id"] = locationID
+	}
+	if err := tx.Model(&item).Updates(updates).Error; err != nil {
+		return item, err
+	}
+
+	movement := models.StockMovement{
+		UserID:     item.UserID,
+		ItemID:     item.ID,
+		LocationID: item.LocationID,
+		Quantity:   quantity,
+		UnitCost:   unitCost,
+		Currency:   item.Currency,
+		Reason:     models.MovementPurchase,
+		Reference:  order.Number,
+		OccurredAt: receivedAt,
+	}
+	return item, tx.Create(&movement).Error
+}
+
+// applyPurchaseOrderRequest copies a purchase order payload onto an order after checking
+// the supplier, currency and items. It writes the error response itself on failure.
+func applyPurchaseOrderRequest(c *gin.Context, order *models.PurchaseOrder, orderData purchaseOrderRequest) bool {
+	supplier, ok := findUserSupplier(c, orderData.SupplierID)
+	if !ok {
+		return false
+	}
+
+	code := supplier.Currency
+	if orderData.Currency != "" {
+		code = orderData.Currency
+	}
+	if code == "" {
+		code = defaultCurrency()
+	}
+	code, err := currency.Normalize(code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency: " + err.Error()})
+		return false
+	}
+
+	itemIDs := make([]uint, 0, len(orderData.Lines))
+	for _, line := range orderData.Lines {
+		itemIDs = append(itemIDs, line.ItemID)
+	}
+	if len(itemIDs) > 0 {
+		var count int64
+		DB := db.GetDB()
+		if result := DB.Model(&models.Item{}).Where("id IN ? AND user_id = ?", itemIDs, order.UserID).Distinct("id").Count(&count); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check items: " + result.Error.Error()})
+			return false
+		}
+		distinct := make(map[uint]bool)
+		for _, id := range itemIDs {
+			distinct[id] = true
+		}
+		if int(count) != len(distinct) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Item not found"})
+			return false
+		}
+	}
+
+	order.SupplierID = supplier.ID
+	order.Supplier = supplier
+	order.Number = orderData.Number
+	order.Currency = code
+	order.Notes = orderData.Notes
+	order.ExpectedAt = orderData.ExpectedAt
+	order.Lines = make([]models.PurchaseOrderLine, 0, len(orderData.Lines))
+	for _, line := range orderData.Lines {
+		order.Lines = append(order.Lines, models.PurchaseOrderLine{ItemID: line.ItemID, Quantity: line.Quantity, UnitCost: line.UnitCost})
+	}
+	return true
+}
+
+// findUserPurchaseOrder loads the purchase order named in the URL, with its supplier and lines,
+// if it belongs to the authenticated user. It writes the error response itself and reports
+// whether the handler should continue.
+func findUserPurchaseOrder(c *gin.Context) (models.PurchaseOrder, bool) {
+	var order models.PurchaseOrder
+
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return order, false
+	}
+
+	DB := db.GetDB()
+	if result := DB.Preload("Supplier").Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Where("id = ? AND user_id = ?", c.Param("order_id"), userID).First(&order); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Purchase order not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve purchase order: " + result.Error.Error()})
+		}
+		return order, false
+	}
+	return order, true
+}
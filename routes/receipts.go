@@ -0,0 +1,168 @@
+// routes/receipts.go
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/events"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// maxReceiptSize caps receipt uploads at 10 MB
+const maxReceiptSize = 10 << 20
+
+// receiptExtensions are the file types accepted as receipts
+var receiptExtensions = map[string]bool{
+	".pdf":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".webp": true,
+}
+
+// ReceiptRoutes sets up the routes for attaching purchase receipts to items
+func ReceiptRoutes(router *gin.Engine) {
+	receiptRoutes := router.Group("/items")
+	receiptRoutes.Use(middleware.AuthMiddleware())
+	{
+		receiptRoutes.POST("/:item_id/receipt", UploadReceipt())
+		receiptRoutes.GET("/:item_id/receipt", GetReceipt())
+		receiptRoutes.DELETE("/:item_id/receipt", DeleteReceipt())
+	}
+}
+
+// UploadReceipt stores a receipt file (multipart field "receipt") for an item, replacing any previous one
+func UploadReceipt() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		file, err := c.FormFile("receipt")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+		if file.Size > maxReceiptSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Receipt must be 10 MB or smaller"})
+			return
+		}
+		extension := strings.ToLower(filepath.Ext(file.Filename))
+		if !receiptExtensions[extension] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Receipt must be a PDF, JPEG, PNG or WebP file"})
+			return
+		}
+
+		directory := filepath.Join(uploadDir(), "receipts")
+		if err := os.MkdirAll(directory, 0o755); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store receipt: " + err.Error()})
+			return
+		}
+		path := filepath.Join(directory, fmt.Sprintf("item-%d-%d%s", item.ID, time.Now().UnixNano(), extension))
+		if err := c.SaveUploadedFile(file, path); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store receipt: " + err.Error()})
+			return
+		}
+
+		previous := item
+		item.ReceiptPath = path
+		item.ReceiptUrl = fmt.Sprintf("/items/%d/receipt", item.ID)
+
+		var outbox events.Outbox
+		DB := db.GetDB()
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&item).Updates(map[string]interface{}{"receipt_path": item.ReceiptPath, "receipt_url": item.ReceiptUrl}).Error; err != nil {
+				return err
+			}
+			return outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionUpdated, item)
+		})
+		if err != nil {
+			os.Remove(path)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item: " + err.Error()})
+			return
+		}
+		removeReceiptFile(previous)
+		outbox.Broadcast()
+
+		c.JSON(http.StatusOK, gin.H{"item_id": item.ID, "receipt_url": item.ReceiptUrl})
+	}
+}
+
+// GetReceipt serves an item's uploaded receipt
+func GetReceipt() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+		if item.ReceiptPath == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item has no receipt"})
+			return
+		}
+
+		c.File(item.ReceiptPath)
+	}
+}
+
+// DeleteReceipt removes an item's receipt
+func DeleteReceipt() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+		if item.ReceiptPath == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item has no receipt"})
+			return
+		}
+
+		previous := item
+		item.ReceiptPath = ""
+		item.ReceiptUrl = ""
+
+		var outbox events.Outbox
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&item).Updates(map[string]interface{}{"receipt_path": item.ReceiptPath, "receipt_url": item.ReceiptUrl}).Error; err != nil {
+				return err
+			}
+			return outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionUpdated, item)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item: " + err.Error()})
+			return
+		}
+		removeReceiptFile(previous)
+		outbox.Broadcast()
+
+		c.JSON(http.StatusOK, gin.H{"message": "Receipt deleted successfully"})
+	}
+}
+
+// uploadDir is where uploaded files are stored (UPLOAD_DIR, default "uploads")
+func uploadDir() string {
+	if directory := os.Getenv("UPLOAD_DIR"); directory != "" {
+		return directory
+	}
+	return "uploads"
+}
+
+// removeReceiptFile deletes an item's receipt from disk, logging rather than failing
+func removeReceiptFile(item models.Item) {
+	if item.ReceiptPath == "" {
+		return
+	}
+	if err := os.Remove(item.ReceiptPath); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Failed to remove receipt for item %d: %v\n", item.ID, err)
+	}
+}
@@ -0,0 +1,304 @@
+// routes/reservations.go
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/events"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errReservationClosed is returned when fulfilling or releasing a reservation that is no longer active
+var errReservationClosed = fmt.Errorf("reservation is not active")
+
+// errBelowReserved is returned when an edit would leave less stock than is reserved
+var errBelowReserved = fmt.Errorf("quantity is below the reserved quantity")
+
+// ReservationRoutes sets up the routes for holding item stock
+func ReservationRoutes(router *gin.Engine) {
+	itemReservationRoutes := router.Group("/items")
+	itemReservationRoutes.Use(middleware.AuthMiddleware())
+	{
+		itemReservationRoutes.GET("/:item_id/reservations", GetItemReservations())
+		itemReservationRoutes.POST("/:item_id/reservations", CreateReservation())
+	}
+
+	reservationRoutes := router.Group("/reservations")
+	reservationRoutes.Use(middleware.AuthMiddleware())
+	{
+		reservationRoutes.GET("/", GetReservations())
+		reservationRoutes.GET("/:reservation_id", GetReservation())
+		reservationRoutes.POST("/:reservation_id/fulfil", FulfilReservation())
+		reservationRoutes.POST("/:reservation_id/release", ReleaseReservation())
+	}
+}
+
+// CreateReservation holds some of an item's available stock for a person or project
+func CreateReservation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reservationData struct {
+			Quantity  int        `json:"quantity" binding:"required,min=1"`
+			Holder    string     `json:"holder" binding:"required"`
+			Note      string     `json:"note"`
+			ExpiresAt *time.Time `json:"expires_at"`
+		}
+		if err := c.ShouldBindJSON(&reservationData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+		if reservationData.ExpiresAt != nil && !reservationData.ExpiresAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+			return
+		}
+
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		reservation := models.Reservation{
+			UserID:    item.UserID,
+			ItemID:    item.ID,
+			Quantity:  reservationData.Quantity,
+			Holder:    reservationData.Holder,
+			Note:      reservationData.Note,
+			Status:    models.ReservationActive,
+			ExpiresAt: reservationData.ExpiresAt,
+		}
+		var outbox events.Outbox
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, item.ID).Error; err != nil {
+				return err
+			}
+			if item.Available() < reservation.Quantity {
+				return errInsufficientStock
+			}
+			if err := tx.Omit(clause.Associations).Create(&reservation).Error; err != nil {
+				return err
+			}
+			item.ReservedQuantity += reservation.Quantity
+			if err := tx.Model(&item).Update("reserved_quantity", item.ReservedQuantity).Error; err != nil {
+				return err
+			}
+			return outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionUpdated, item)
+		})
+		if err == errInsufficientStock {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Only %d available to reserve", item.Available())})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reservation: " + err.Error()})
+			return
+		}
+		outbox.Broadcast()
+
+		c.JSON(http.StatusCreated, gin.H{"reservation": reservation, "available_quantity": item.Available()})
+	}
+}
+
+// GetItemReservations lists an item's reservations, active ones by default (status=all for every one)
+func GetItemReservations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		query := DB.Where("item_id = ?", item.ID)
+		if status := c.DefaultQuery("status", models.ReservationActive); status != "all" {
+			query = query.Where("status = ?", status)
+		}
+		var reservations []models.Reservation
+		if result := query.Order("created_at").Find(&reservations); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reservations: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"item_id":            item.ID,
+			"quantity":           item.Quantity,
+			"reserved_quantity":  item.ReservedQuantity,
+			"available_quantity": item.Available(),
+			"reservations":       reservations,
+		})
+	}
+}
+
+// GetReservations lists the user's reservations across items.
+//
+//	status=<status>|all  active by default
+//	holder=<name>        only one holder's reservations
+func GetReservations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		DB := db.GetDB()
+		query := DB.Preload("Item").Where("user_id = ?", userID)
+		if status := c.DefaultQuery("status", models.ReservationActive); status != "all" {
+			query = query.Where("status = ?", status)
+		}
+		if holder := c.Query("holder"); holder != "" {
+			query = query.Where("holder = ?", holder)
+		}
+		var reservations []models.Reservation
+		if result := query.Order("expires_at ASC NULLS LAST, created_at").Find(&reservations); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reservations: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"reservations": reservations})
+	}
+}
+
+// GetReservation retrieves a single reservation
+func GetReservation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reservation, ok := findUserReservation(c)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"reservation": reservation})
+	}
+}
+
+// FulfilReservation hands the reserved stock over to its holder, taking it out of stock
+func FulfilReservation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reservation, ok := findUserReservation(c)
+		if !ok {
+			return
+		}
+
+		var item models.Item
+		var outbox events.Outbox
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := lockActiveReservation(tx, &reservation); err != nil {
+				return err
+			}
+			if err := models.CloseReservation(tx, &reservation, models.ReservationFulfilled); err != nil {
+				return err
+			}
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, reservation.ItemID).Error; err != nil {
+				return err
+			}
+			if _, err := adjustItemStock(tx, &item, -reservation.Quantity, models.MovementReservation, fmt.Sprintf("reservation:%d", reservation.ID)); err != nil {
+				return err
+			}
+			return outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionUpdated, item)
+		})
+		if err == errReservationClosed {
+			c.JSON(http.StatusConflict, gin.H{"error": "Reservation is " + reservation.Status})
+			return
+		}
+		if err == errInsufficientStock {
+			c.JSON(http.StatusConflict, gin.H{"error": "Not enough stock on hand to fulfil the reservation"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fulfil reservation: " + err.Error()})
+			return
+		}
+		outbox.Broadcast()
+
+		reservation.Item = item
+		c.JSON(http.StatusOK, gin.H{"reservation": reservation})
+	}
+}
+
+// ReleaseReservation cancels a hold, making its stock available again
+func ReleaseReservation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reservation, ok := findUserReservation(c)
+		if !ok {
+			return
+		}
+
+		var outbox events.Outbox
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := lockActiveReservation(tx, &reservation); err != nil {
+				return err
+			}
+			if err := models.CloseReservation(tx, &reservation, models.ReservationReleased); err != nil {
+				return err
+			}
+			if err := tx.First(&reservation.Item, reservation.ItemID).Error; err != nil {
+				return err
+			}
+			return outbox.Publish(tx, reservation.UserID, events.EntityItem, reservation.ItemID, events.ActionUpdated, reservation.Item)
+		})
+		if err == errReservationClosed {
+			c.JSON(http.StatusConflict, gin.H{"error": "Reservation is " + reservation.Status})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release reservation: " + err.Error()})
+			return
+		}
+		outbox.Broadcast()
+
+		c.JSON(http.StatusOK, gin.H{"reservation": reservation})
+	}
+}
+
+// lockActiveReservation reloads a reservation under a row lock and checks it is still active
+func lockActiveReservation(tx *gorm.DB, reservation *models.Reservation) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(reservation, reservation.ID).Error; err != nil {
+		return err
+	}
+	if reservation.Status != models.ReservationActive {
+		return errReservationClosed
+	}
+	return nil
+}
+
+// findUserReservation loads a reservation (with its item) if it belongs to the authenticated user.
+// It writes the error response itself and reports whether the handler should continue.
+func findUserReservation(c *gin.Context) (models.Reservation, bool) {
+	var reservation models.Reservation
+
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return reservation, false
+	}
+
+	DB := db.GetDB()
+	if result := DB.Preload("Item").Where("id = ? AND user_id = ?", c.Param("reservation_id"), userID).First(&reservation); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reservation not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reservation: " + result.Error.Error()})
+		}
+		return reservation, false
+	}
+	return reservation, true
+}
+
+// checkReservedQuantity locks an item being edited and refuses a quantity lower than what is
+// reserved, which would make the available quantity negative
+func checkReservedQuantity(tx *gorm.DB, item *models.Item) error {
+	var current models.Item
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "reserved_quantity").First(&current, item.ID).Error; err != nil {
+		return err
+	}
+	item.ReservedQuantity = current.ReservedQuantity
+	if item.Quantity < current.ReservedQuantity {
+		return errBelowReserved
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// routes/sessions.go
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sessionTTL is how long a session lasts without being refreshed
+const sessionTTL = 7 * 24 * time.Hour
+
+// maxUserAgentLength caps what is stored from the User-Agent header
+const maxUserAgentLength = 512
+
+// errSessionEnded is returned when a refresh token's session is revoked, expired or replayed
+var errSessionEnded = fmt.Errorf("session has ended")
+
+// SessionRoutes sets up the routes for listing and revoking signed-in devices
+func SessionRoutes(router *gin.Engine) {
+	sessionRoutes := router.Group("/auth/sessions")
+	sessionRoutes.Use(middleware.AuthMiddleware())
+	{
+		sessionRoutes.GET("/", GetSessions())
+		sessionRoutes.DELETE("/:session_id", RevokeSession())
+	}
+}
+
+// GetSessions lists the user's active sessions, marking the one making the request
+func GetSessions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		var sessions []models.Session
+		DB := db.GetDB()
+		if result := DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+			Order("last_seen_at DESC").Find(&sessions); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sessions: " + result.Error.Error()})
+			return
+		}
+
+		currentID := middleware.GetSessionID(c)
+		results := make([]gin.H, len(sessions))
+		for i, session := range sessions {
+			results[i] = gin.H{
+				"id":           session.ID,
+				"device_name":  session.DeviceName,
+				"ip":           session.IP,
+				"user_agent":   session.UserAgent,
+				"created_at":   session.CreatedAt,
+				"last_seen_at": session.LastSeenAt,
+				"expires_at":   session.ExpiresAt,
+				"current":      session.ID == currentID,
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"sessions": results})
+	}
+}
+
+// RevokeSession signs a device out. Its refresh token and any access tokens it holds stop working.
+func RevokeSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		var session models.Session
+		DB := db.GetDB()
+		if result := DB.Where("id = ? AND user_id = ?", c.Param("session_id"), userID).First(&session); result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve session: " + result.Error.Error()})
+			}
+			return
+		}
+		if session.RevokedAt != nil {
+			c.JSON(http.StatusOK, gin.H{"message": "Session was already revoked"})
+			return
+		}
+
+		if result := DB.Model(&session).Update("revoked_at", time.Now()); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+	}
+}
+
+// startSession records a new signed-in device for the user
+func startSession(c *gin.Context, user models.User) (models.Session, error) {
+	now := time.Now()
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	session := models.Session{
+		UserID:         user.ID,
+		DeviceName:     deviceName(c),
+		IP:             c.ClientIP(),
+		UserAgent:      userAgent,
+		RefreshTokenID: randomURLToken(),
+		LastSeenAt:     now,
+		ExpiresAt:      now.Add(sessionTTL),
+	}
+	if err := db.GetDB().Create(&session).Error; err != nil {
+		return session, err
+	}
+	return session, nil
+}
+
+// rotateSession checks a refresh token against its session and gives the session a new refresh
+// token ID. A token that isn't the newest one has been used before, so the session is revoked.
+func rotateSession(c *gin.Context, sessionID uint, userID uint, tokenID string) (models.Session, error) {
+	var session models.Session
+	reused := false
+	err := db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errSessionEnded
+			}
+			return err
+		}
+		if !session.Active() {
+			return errSessionEnded
+		}
+
+		now := time.Now()
+		if tokenID == "" || tokenID != session.RefreshTokenID {
+			reused = true
+			return tx.Model(&session).Update("revoked_at", now).Error
+		}
+
+		session.RefreshTokenID = randomURLToken()
+		session.LastSeenAt = now
+		session.ExpiresAt = now.Add(sessionTTL)
+		session.IP = c.ClientIP()
+		return tx.Model(&session).Updates(map[string]interface{}{
+			"refresh_token_id": session.RefreshTokenID,
+			"last_seen_at":     session.LastSeenAt,
+			"expires_at":       session.ExpiresAt,
+			"ip":               session.IP,
+		}).Error
+	})
+	if err == nil && reused {
+		fmt.Printf("Refresh token reused for session ID %d of user ID %d, session revoked\n", sessionID, userID)
+		return session, errSessionEnded
+	}
+	return session, err
+}
+
+// deviceName is the X-Device-Name header if the client sends one, otherwise a rough
+// description of the User-Agent such as "Firefox on Windows"
+func deviceName(c *gin.Context) string {
+	if name := strings.TrimSpace(c.GetHeader("X-Device-Name")); name != "" {
+		if len(name) > 100 {
+			name = name[:100]
+		}
+		return name
+	}
+
+	userAgent := c.Request.UserAgent()
+	client := "Unknown client"
+	for _, known := range []struct{ token, name string }{
+		{"Edg/", "Edge"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"}, {"Safari/", "Safari"},
+		{"curl/", "curl"}, {"PostmanRuntime/", "Postman"}, {"python-requests/", "Python"}, {"Go-http-client/", "Go"},
+	} {
+		if strings.Contains(userAgent, known.token) {
+			client = known.name
+			break
+		}
+	}
+	for _, known := range []struct{ token, name string }{
+		{"iPhone", "iOS"}, {"iPad", "iOS"}, {"Android", "Android"}, {"Windows", "Windows"},
+		{"Mac OS X", "macOS"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, known.token) {
+			return client + " on " + known.name
+		}
+	}
+	return client
+}
@@ -0,0 +1,378 @@
+// routes/share_links.go
+package routes
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"github.com/sidhant-sriv/inventory-api/passwords"
+	"gorm.io/gorm"
+)
+
+// sharedItem is what a public share link shows of an item; prices, suppliers and receipts stay private
+type sharedItem struct {
+	ID                uint   `json:"id"`
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	ImageUrl          string `json:"image_url"`
+	Quantity          int    `json:"quantity"`
+	AvailableQuantity int    `json:"available_quantity"`
+	Location          string `json:"location"`
+}
+
+// sharePage is the content of a share link, or why it can't be shown
+type sharePage struct {
+	Title            string       `json:"title"`
+	Description      string       `json:"description"`
+	ImageUrl         string       `json:"image_url"`
+	Items            []sharedItem `json:"items"`
+	ExpiresAt        *time.Time   `json:"expires_at"`
+	PasswordRequired bool         `json:"-"`
+	Error            string       `json:"-"`
+}
+
+// sharePageTemplate renders a share link for people opening it in a browser
+var sharePageTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{if .Title}}{{.Title}}{{else}}Shared inventory{{end}}</title>
+<style>
+body { font-family: sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.4rem; border-bottom: 1px solid #ddd; }
+td.number { text-align: right; }
+img { max-width: 4rem; max-height: 4rem; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+{{if .PasswordRequired}}
+<h1>This link is password protected</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post">
+<label>Password <input type="password" name="password" autofocus required></label>
+<button type="submit">View</button>
+</form>
+{{else if .Error}}
+<h1>{{.Error}}</h1>
+{{else}}
+<h1>{{.Title}}</h1>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+{{if .Items}}
+<table>
+<thead><tr><th></th><th>Item</th><th>Location</th><th>Quantity</th><th>Available</th></tr></thead>
+<tbody>
+{{range .Items}}<tr>
+<td>{{if .ImageUrl}}<img src="{{.ImageUrl}}" alt="">{{end}}</td>
+<td>{{.Name}}{{if .Description}}<br><small>{{.Description}}</small>{{end}}</td>
+<td>{{.Location}}</td>
+<td class="number">{{.Quantity}}</td>
+<td class="number">{{.AvailableQuantity}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+{{else}}
+<p>There are no items here.</p>
+{{end}}
+{{if .ExpiresAt}}<p><small>This link expires {{.ExpiresAt.Format "2 Jan 2006 15:04 MST"}}.</small></p>{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// ShareLinkRoutes sets up the routes for public, read-only share links
+func ShareLinkRoutes(router *gin.Engine) {
+	// Public routes for opening a link; POST sends the password from the HTML form
+	router.GET("/share/:token", ViewShareLink())
+	router.POST("/share/:token", ViewShareLink())
+
+	locationLinkRoutes := router.Group("/locations")
+	locationLinkRoutes.Use(middleware.AuthMiddleware())
+	{
+		locationLinkRoutes.POST("/:location_id/share-links", CreateShareLink(findShareLocation))
+	}
+
+	itemLinkRoutes := router.Group("/items")
+	itemLinkRoutes.Use(middleware.AuthMiddleware())
+	{
+		itemLinkRoutes.POST("/:item_id/share-links", CreateShareLink(findShareItem))
+	}
+
+	shareLinkRoutes := router.Group("/share-links")
+	shareLinkRoutes.Use(middleware.AuthMiddleware())
+	{
+		shareLinkRoutes.GET("/", GetShareLinks())
+		shareLinkRoutes.DELETE("/:link_id", RevokeShareLink())
+	}
+}
+
+// CreateShareLink creates a public link to a location or item, optionally with a password
+// and an expiry. The link's token is only returned in this response.
+func CreateShareLink(findTarget func(*gin.Context) (shareTarget, bool)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		target, ok := findTarget(c)
+		if !ok {
+			return
+		}
+
+		var linkRequest struct {
+			Label     string     `json:"label"`
+			Password  string     `json:"password" binding:"omitempty,min=6,max=128"`
+			ExpiresAt *time.Time `json:"expires_at"`
+		}
+		if err := c.ShouldBindJSON(&linkRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+		if linkRequest.ExpiresAt != nil && !linkRequest.ExpiresAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+			return
+		}
+
+		token := randomURLToken()
+		link := models.ShareLink{
+			OwnerID:     target.OwnerID,
+			Label:       linkRequest.Label,
+			LocationID:  target.LocationID,
+			ItemID:      target.ItemID,
+			TokenHash:   hashUserToken(token),
+			TokenPrefix: token[:6],
+			ExpiresAt:   linkRequest.ExpiresAt,
+		}
+		if linkRequest.Password != "" {
+			if err := passwords.CheckLength(linkRequest.Password); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+				return
+			}
+			hash, err := passwords.Hash(linkRequest.Password)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password: " + err.Error()})
+				return
+			}
+			link.PasswordHash = hash
+			link.HasPassword = true
+		}
+
+		DB := db.GetDB()
+		if result := DB.Create(&link); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link: " + result.Error.Error()})
+			return
+		}
+		fmt.Printf("User ID %d created share link ID %d\n", link.OwnerID, link.ID)
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":    "Share link created. Store the URL now; it can't be shown again.",
+			"url":        "/share/" + token,
+			"share_link": link,
+		})
+	}
+}
+
+// GetShareLinks lists the user's share links. Revoked links are included with include_revoked=true.
+func GetShareLinks() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		DB := db.GetDB()
+		query := DB.Where("owner_id = ?", userID)
+		if c.Query("include_revoked") != "true" {
+			query = query.Where("revoked_at IS NULL")
+		}
+		var links []models.ShareLink
+		if result := query.Order("created_at DESC").Find(&links); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve share links: " + result.Error.Error()})
+			return
+		}
+
+		results := make([]gin.H, len(links))
+		for i, link := range links {
+			results[i] = gin.H{"share_link": link, "active": link.Active()}
+		}
+		c.JSON(http.StatusOK, gin.H{"share_links": results})
+	}
+}
+
+// RevokeShareLink stops a share link from working. The record is kept with its view count.
+func RevokeShareLink() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		var link models.ShareLink
+		DB := db.GetDB()
+		if result := DB.Where("id = ? AND owner_id = ?", c.Param("link_id"), userID).First(&link); result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve share link: " + result.Error.Error()})
+			}
+			return
+		}
+		if link.RevokedAt != nil {
+			c.JSON(http.StatusOK, gin.H{"message": "Share link was already revoked", "share_link": link})
+			return
+		}
+
+		now := time.Now()
+		if result := DB.Model(&link).Update("revoked_at", now); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share link: " + result.Error.Error()})
+			return
+		}
+		link.RevokedAt = &now
+
+		c.JSON(http.StatusOK, gin.H{"message": "Share link revoked", "share_link": link})
+	}
+}
+
+// ViewShareLink shows what a share link points to, as JSON or as an HTML page for browsers
+// (or ?format=json|html). Password-protected links take the password in the
+// X-Share-Password header or a "password" form field; wrong guesses are throttled.
+func ViewShareLink() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		c.Header("X-Robots-Tag", "noindex")
+		c.Header("Referrer-Policy", "no-referrer")
+
+		var link models.ShareLink
+		DB := db.GetDB()
+		result := DB.Where("token_hash = ?", hashUserToken(c.Param("token"))).First(&link)
+		if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+			renderSharePage(c, http.StatusInternalServerError, sharePage{Error: "Failed to retrieve share link"})
+			return
+		}
+		if result.Error == gorm.ErrRecordNotFound || !link.Active() {
+			renderSharePage(c, http.StatusNotFound, sharePage{Error: "This link doesn't exist or has expired"})
+			return
+		}
+
+		if link.PasswordHash != "" {
+			password := c.GetHeader("X-Share-Password")
+			if password == "" {
+				password = c.PostForm("password")
+			}
+			if password == "" {
+				renderSharePage(c, http.StatusUnauthorized, sharePage{PasswordRequired: true, Error: "A password is required"})
+				return
+			}
+			attempt := beginThrottledAttempt(c,
+				throttleLimit{models.ShareLinkThrottleKey(link.ID), loginMaxFailures()},
+				throttleLimit{models.IPThrottleKey(c.ClientIP()), loginIPMaxFailures()},
+			)
+			if attempt == nil {
+				return
+			}
+			if match, _, _ := passwords.Verify(password, link.PasswordHash); !match {
+				attempt.fail(c, nil)
+				renderSharePage(c, http.StatusUnauthorized, sharePage{PasswordRequired: true, Error: "Incorrect password"})
+				return
+			}
+			attempt.refund()
+		}
+
+		page, err := sharedContent(DB, link)
+		if err == gorm.ErrRecordNotFound {
+			renderSharePage(c, http.StatusNotFound, sharePage{Error: "This link doesn't exist or has expired"})
+			return
+		}
+		if err != nil {
+			renderSharePage(c, http.StatusInternalServerError, sharePage{Error: "Failed to retrieve shared items"})
+			return
+		}
+
+		if result := DB.Model(&link).UpdateColumns(map[string]interface{}{
+			"view_count":     gorm.Expr("view_count + 1"),
+			"last_viewed_at": time.Now(),
+		}); result.Error != nil {
+			fmt.Printf("Error counting view of share link ID %d: %v\n", link.ID, result.Error)
+		}
+
+		renderSharePage(c, http.StatusOK, page)
+	}
+}
+
+// sharedContent loads what a share link shows: every owner's item in the location and the
+// locations inside it, or the single item
+func sharedContent(DB *gorm.DB, link models.ShareLink) (sharePage, error) {
+	page := sharePage{ExpiresAt: link.ExpiresAt}
+	var items []models.Item
+
+	if link.LocationID != nil {
+		var location models.Location
+		if err := DB.Where("id = ? AND user_id = ?", *link.LocationID, link.OwnerID).First(&location).Error; err != nil {
+			return page, err
+		}
+		page.Title, page.Description, page.ImageUrl = location.Name, location.Description, location.ImageUrl
+
+		subtree, err := locationSubtreeIDs(DB, link.OwnerID, location.ID)
+		if err != nil {
+			return page, err
+		}
+		if err := DB.Preload("Location").Where("user_id = ? AND location_id IN ?", link.OwnerID, subtree).
+			Order("name").Find(&items).Error; err != nil {
+			return page, err
+		}
+	} else {
+		var item models.Item
+		if err := DB.Preload("Location").Where("id = ? AND user_id = ?", *link.ItemID, link.OwnerID).First(&item).Error; err != nil {
+			return page, err
+		}
+		page.Title, page.Description, page.ImageUrl = item.Name, item.Description, item.ImageUrl
+		items = []models.Item{item}
+	}
+
+	page.Items = make([]sharedItem, len(items))
+	for i, item := range items {
+		page.Items[i] = sharedItem{
+			ID:                item.ID,
+			Name:              item.Name,
+			Description:       item.Description,
+			ImageUrl:          item.ImageUrl,
+			Quantity:          item.Quantity,
+			AvailableQuantity: item.AvailableQuantity,
+			Location:          item.Location.Name,
+		}
+	}
+	return page, nil
+}
+
+// renderSharePage responds with a share link's page in the format the client asked for
+func renderSharePage(c *gin.Context, status int, page sharePage) {
+	format := c.Query("format")
+	if format == "" {
+		format = c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML)
+	}
+
+	if format != "html" && format != gin.MIMEHTML {
+		if page.Error != "" {
+			c.JSON(status, gin.H{"error": page.Error, "password_required": page.PasswordRequired})
+			return
+		}
+		c.JSON(status, page)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := sharePageTemplate.Execute(&body, page); err != nil {
+		fmt.Printf("Error rendering share page: %v\n", err)
+		c.String(http.StatusInternalServerError, "Failed to render page")
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", body.Bytes())
+}
@@ -0,0 +1,383 @@
+// routes/shares.go
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// permissionOwner is reported for the user's own locations and items, alongside the share permissions
+const permissionOwner = "owner"
+
+// errShareWithSelf is returned when the owner tries to share with their own account
+var errShareWithSelf = fmt.Errorf("can't share with yourself")
+
+// shareTarget is the location or item whose shares a request manages
+type shareTarget struct {
+	OwnerID    uint
+	LocationID *uint
+	ItemID     *uint
+}
+
+// ShareRoutes sets up the routes for sharing locations and items with other users
+func ShareRoutes(router *gin.Engine) {
+	locationShareRoutes := router.Group("/locations")
+	locationShareRoutes.Use(middleware.AuthMiddleware())
+	{
+		locationShareRoutes.GET("/:location_id/shares", GetShares(findShareLocation))
+		locationShareRoutes.POST("/:location_id/shares", GrantShare(findShareLocation))
+		locationShareRoutes.DELETE("/:location_id/shares/:share_id", RevokeShare(findShareLocation))
+	}
+
+	itemShareRoutes := router.Group("/items")
+	itemShareRoutes.Use(middleware.AuthMiddleware())
+	{
+		itemShareRoutes.GET("/:item_id/shares", GetShares(findShareItem))
+		itemShareRoutes.POST("/:item_id/shares", GrantShare(findShareItem))
+		itemShareRoutes.DELETE("/:item_id/shares/:share_id", RevokeShare(findShareItem))
+	}
+
+	shareRoutes := router.Group("/shares")
+	shareRoutes.Use(middleware.AuthMiddleware())
+	{
+		shareRoutes.GET("/", GetSharedWithMe())
+	}
+}
+
+// GetShares lists who a location or item is shared with. Only the owner can see this.
+func GetShares(findTarget func(*gin.Context) (shareTarget, bool)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		target, ok := findTarget(c)
+		if !ok {
+			return
+		}
+
+		var shares []models.Share
+		DB := db.GetDB()
+		if result := target.scope(DB).Preload("User").Order("created_at").Find(&shares); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve shares: " + result.Error.Error()})
+			return
+		}
+
+		results := make([]gin.H, len(shares))
+		for i, share := range shares {
+			results[i] = shareJSON(share)
+		}
+		c.JSON(http.StatusOK, gin.H{"shares": results})
+	}
+}
+
+// GrantShare shares a location or item with another user, identified by email, or by user_id
+// for someone the owner already shares with (either way round). Sharing again with the same
+// user changes the permission. The reply is the same whether or not the address has an
+// account: a verified account gets access straight away, anyone else once they verify it.
+func GrantShare(findTarget func(*gin.Context) (shareTarget, bool)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		target, ok := findTarget(c)
+		if !ok {
+			return
+		}
+
+		var shareRequest struct {
+			UserID     uint   `json:"user_id"`
+			Email      string `json:"email" binding:"omitempty,email"`
+			Permission string `json:"permission" binding:"required,oneof=viewer editor"`
+		}
+		if err := c.ShouldBindJSON(&shareRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+		if (shareRequest.UserID == 0) == (shareRequest.Email == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Give either user_id or email"})
+			return
+		}
+
+		owner, ok := findCurrentUser(c)
+		if !ok {
+			return
+		}
+
+		var share models.Share
+		status := http.StatusOK
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			// Lock the target so the same address isn't invited twice at once
+			if err := target.lock(tx); err != nil {
+				return err
+			}
+
+			email := strings.ToLower(strings.TrimSpace(shareRequest.Email))
+			var grantee *uint
+			if shareRequest.UserID != 0 {
+				user, err := findShareContact(tx, target.OwnerID, shareRequest.UserID)
+				if err != nil {
+					return err
+				}
+				grantee, email = &user.ID, strings.ToLower(user.Email)
+			} else {
+				// Verified accounts get access straight away; anyone else once they verify the address
+				var user models.User
+				result := tx.Where("LOWER(email) = ? AND email_verified_at IS NOT NULL", email).First(&user)
+				if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+					return result.Error
+				}
+				if result.Error == nil {
+					grantee = &user.ID
+				}
+			}
+			if email == strings.ToLower(owner.Email) || (grantee != nil && *grantee == target.OwnerID) {
+				return errShareWithSelf
+			}
+
+			query := target.scope(tx).Where("user_id IS NULL AND LOWER(email) = ?", email)
+			if grantee != nil {
+				query = target.scope(tx).Where("user_id = ?", *grantee)
+			}
+			result := query.First(&share)
+			if result.Error == gorm.ErrRecordNotFound {
+				status = http.StatusCreated
+				share = models.Share{
+					OwnerID:    target.OwnerID,
+					UserID:     grantee,
+					Email:      email,
+					LocationID: target.LocationID,
+					ItemID:     target.ItemID,
+					Permission: shareRequest.Permission,
+				}
+				return tx.Omit(clause.Associations).Create(&share).Error
+			}
+			if result.Error != nil {
+				return result.Error
+			}
+			share.Permission = shareRequest.Permission
+			return tx.Model(&share).Update("permission", share.Permission).Error
+		})
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if err == errShareWithSelf {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You can't share with yourself"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to share: " + err.Error()})
+			return
+		}
+		fmt.Printf("User ID %d shared as %s (share ID %d)\n", target.OwnerID, share.Permission, share.ID)
+
+		c.JSON(status, gin.H{"share": shareJSON(share)})
+	}
+}
+
+// findShareContact loads a user the owner already shares with, in either direction. Anyone
+// else is reported as not found, so user IDs can't be probed for accounts.
+func findShareContact(tx *gorm.DB, ownerID uint, userID uint) (models.User, error) {
+	var user models.User
+	var count int64
+	if err := tx.Model(&models.Share{}).
+		Where("(owner_id = ? AND user_id = ?) OR (owner_id = ? AND user_id = ?)", ownerID, userID, userID, ownerID).
+		Count(&count).Error; err != nil {
+		return user, err
+	}
+	if count == 0 {
+		return user, gorm.ErrRecordNotFound
+	}
+	return user, tx.First(&user, userID).Error
+}
+
+// RevokeShare stops sharing a location or item with a user
+func RevokeShare(findTarget func(*gin.Context) (shareTarget, bool)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		target, ok := findTarget(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		result := target.scope(DB).Where("id = ?", c.Param("share_id")).Delete(&models.Share{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share: " + result.Error.Error()})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Share revoked"})
+	}
+}
+
+// GetSharedWithMe lists the locations and items other users have shared with the current user
+func GetSharedWithMe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		var shares []models.Share
+		DB := db.GetDB()
+		if result := DB.Preload("Owner").Preload("Location").Preload("Item").
+			Where("user_id = ?", userID).Order("created_at").Find(&shares); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve shares: " + result.Error.Error()})
+			return
+		}
+
+		results := make([]gin.H, len(shares))
+		for i, share := range shares {
+			result := gin.H{
+				"id":          share.ID,
+				"owner":       gin.H{"id": share.Owner.ID, "name": share.Owner.Name},
+				"location_id": share.LocationID,
+				"item_id":     share.ItemID,
+				"permission":  share.Permission,
+				"created_at":  share.CreatedAt,
+			}
+			if share.Location != nil {
+				result["name"] = share.Location.Name
+			}
+			if share.Item != nil {
+				result["name"] = share.Item.Name
+			}
+			results[i] = result
+		}
+		c.JSON(http.StatusOK, gin.H{"shares": results})
+	}
+}
+
+// findShareLocation loads the location from the URL as a share target; only its owner may manage shares
+func findShareLocation(c *gin.Context) (shareTarget, bool) {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return shareTarget{}, false
+	}
+
+	var location models.Location
+	DB := db.GetDB()
+	if result := DB.Where("id = ? AND user_id = ?", c.Param("location_id"), userID).First(&location); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Location not found or you don't own it"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve location: " + result.Error.Error()})
+		}
+		return shareTarget{}, false
+	}
+	return shareTarget{OwnerID: userID, LocationID: &location.ID}, true
+}
+
+// findShareItem loads the item from the URL as a share target; only its owner may manage shares
+func findShareItem(c *gin.Context) (shareTarget, bool) {
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return shareTarget{}, false
+	}
+
+	var item models.Item
+	DB := db.GetDB()
+	if result := DB.Where("id = ? AND user_id = ?", c.Param("item_id"), userID).First(&item); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found or you don't own it"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve item: " + result.Error.Error()})
+		}
+		return shareTarget{}, false
+	}
+	return shareTarget{OwnerID: userID, ItemID: &item.ID}, true
+}
+
+// lock takes a row lock on the target's location or item
+func (target shareTarget) lock(tx *gorm.DB) error {
+	if target.LocationID != nil {
+		return tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Location{}, *target.LocationID).Error
+	}
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Item{}, *target.ItemID).Error
+}
+
+// scope restricts a share query to the target's shares
+func (target shareTarget) scope(DB *gorm.DB) *gorm.DB {
+	if target.LocationID != nil {
+		return DB.Where("owner_id = ? AND location_id = ?", target.OwnerID, *target.LocationID)
+	}
+	return DB.Where("owner_id = ? AND item_id = ?", target.OwnerID, *target.ItemID)
+}
+
+// shareJSON shows a share to its owner. Only the address it was sent to is shown, so shares
+// look the same whether or not the address has an account.
+func shareJSON(share models.Share) gin.H {
+	email := share.Email
+	if email == "" && share.User != nil {
+		// Shares made before addresses were recorded
+		email = share.User.Email
+	}
+	return gin.H{
+		"id":          share.ID,
+		"email":       email,
+		"location_id": share.LocationID,
+		"item_id":     share.ItemID,
+		"permission":  share.Permission,
+		"created_at":  share.CreatedAt,
+	}
+}
+
+// itemPermission is the user's access to an item: owner, editor, viewer, or "" for none.
+// Items are shared directly or through their location or any location enclosing it.
+func itemPermission(DB *gorm.DB, userID uint, item models.Item) (string, error) {
+	if item.UserID == userID {
+		return permissionOwner, nil
+	}
+	return sharedPermission(DB, userID, item.UserID, item.LocationID, item.ID)
+}
+
+// locationPermission is the user's access to a location: owner (also for public locations),
+// editor, viewer, or "" for none
+func locationPermission(DB *gorm.DB, userID uint, location models.Location) (string, error) {
+	if location.UserID == userID || location.UserID == 0 {
+		return permissionOwner, nil
+	}
+	return sharedPermission(DB, userID, location.UserID, location.ID, 0)
+}
+
+// sharedPermission finds the strongest share the owner has given the user on the item or on
+// the location or any location enclosing it
+func sharedPermission(DB *gorm.DB, userID uint, ownerID uint, locationID uint, itemID uint) (string, error) {
+	var permissions []string
+	result := DB.Table("(?) AS shares", models.CoveringShares(DB, ownerID, locationID, itemID)).
+		Where("user_id = ?", userID).Pluck("permission", &permissions)
+	if result.Error != nil {
+		return "", result.Error
+	}
+
+	strongest := ""
+	for _, permission := range permissions {
+		if models.SharePermissionRank(permission) > models.SharePermissionRank(strongest) {
+			strongest = permission
+		}
+	}
+	return strongest, nil
+}
+
+// visibleItems limits an item query to the user's own items and the items shared with them
+func visibleItems(DB *gorm.DB, userID uint) *gorm.DB {
+	return DB.Where("user_id = ? OR (id, user_id) IN (SELECT item_id, owner_id FROM shares WHERE user_id = ?) OR (location_id, user_id) IN (?)",
+		userID, userID, models.SharedLocations(DB, userID))
+}
+
+// visibleLocations limits a location query to the user's own locations, public locations and
+// the locations shared with them
+func visibleLocations(DB *gorm.DB, userID uint) *gorm.DB {
+	return DB.Where("user_id = ? OR user_id = 0 OR user_id IS NULL OR (id, user_id) IN (?)",
+		userID, models.SharedLocations(DB, userID))
+}
@@ -0,0 +1,155 @@
+// routes/stock.go
+package routes
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StockRoutes sets up the routes for the stock movement ledger
+func StockRoutes(router *gin.Engine) {
+	stockRoutes := router.Group("/items")
+	stockRoutes.Use(middleware.AuthMiddleware())
+	{
+		stockRoutes.GET("/:item_id/movements", GetItemMovements())
+	}
+}
+
+// GetItemMovements lists an item's stock movements, oldest first
+func GetItemMovements() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		var movements []models.StockMovement
+		DB := db.GetDB()
+		if result := DB.Where("item_id = ?", item.ID).Order("occurred_at, id").Find(&movements); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve movements: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"item_id": item.ID, "quantity": item.Quantity, "movements": movements})
+	}
+}
+
+// recordStockChange writes a ledger entry for the difference between an item's
+// previous and current quantity. Incoming stock is valued at the item's purchase price.
+func recordStockChange(tx *gorm.DB, item models.Item, previousQuantity int, reason string, reference string) error {
+	delta := item.Quantity - previousQuantity
+	if delta == 0 {
+		return nil
+	}
+
+	movement := models.StockMovement{
+		UserID:     item.UserID,
+		ItemID:     item.ID,
+		LocationID: item.LocationID,
+		Quantity:   delta,
+		Currency:   itemCurrency(item),
+		Reason:     reason,
+		Reference:  reference,
+		OccurredAt: time.Now(),
+	}
+	if delta > 0 {
+		movement.UnitCost = item.PurchasePrice
+		// The opening balance is dated to the purchase so as-of valuations include it
+		if reason == models.MovementOpening && item.PurchaseDate != nil {
+			movement.OccurredAt = *item.PurchaseDate
+		}
+	}
+	return tx.Create(&movement).Error
+}
+
+// lotUsage is how much stock was taken out of one lot
+type lotUsage struct {
+	LotID     uint   `json:"lot_id"`
+	LotNumber string `json:"lot_number"`
+	Quantity  int    `json:"quantity"`
+}
+
+// adjustItemStock changes an item's quantity by delta and records the movement. Lot-tracked
+// items take stock out of their lots first-expired-first-out, returning what came out of each
+// lot, and new stock goes into a lot named after the reference. The item should be loaded (and
+// locked) by the caller.
+func adjustItemStock(tx *gorm.DB, item *models.Item, delta int, reason string, reference string) ([]lotUsage, error) {
+	if delta == 0 {
+		return nil, nil
+	}
+	if item.Quantity+delta < 0 {
+		return nil, errInsufficientStock
+	}
+
+	var used []lotUsage
+	var lots []models.Lot
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("item_id = ?", item.ID).
+		Order("expires_at ASC NULLS LAST, id").
+		Find(&lots).Error; err != nil {
+		return nil, err
+	}
+
+	if len(lots) > 0 {
+		if delta > 0 {
+			lot := models.Lot{ItemID: item.ID, UserID: item.UserID, LotNumber: reference, Quantity: delta}
+			if err := tx.Create(&lot).Error; err != nil {
+				return nil, err
+			}
+		} else {
+			remaining := -delta
+			for i := range lots {
+				if remaining == 0 {
+					break
+				}
+				take := lots[i].Quantity
+				if take > remaining {
+					take = remaining
+				}
+				if take <= 0 {
+					continue
+				}
+				if err := tx.Model(&lots[i]).Update("quantity", lots[i].Quantity-take).Error; err != nil {
+					return nil, err
+				}
+				used = append(used, lotUsage{LotID: lots[i].ID, LotNumber: lots[i].LotNumber, Quantity: take})
+				remaining -= take
+			}
+			if remaining > 0 {
+				return nil, errInsufficientStock
+			}
+		}
+	}
+
+	previousQuantity := item.Quantity
+	item.Quantity += delta
+	if err := tx.Model(item).Update("quantity", item.Quantity).Error; err != nil {
+		return nil, err
+	}
+	return used, recordStockChange(tx, *item, previousQuantity, reason, reference)
+}
+
+// itemCurrency returns an item's currency, falling back to the default currency
+func itemCurrency(item models.Item) string {
+	if item.Currency != "" {
+		return strings.ToUpper(item.Currency)
+	}
+	return defaultCurrency()
+}
+
+// defaultCurrency is DEFAULT_CURRENCY, or USD if unset
+func defaultCurrency() string {
+	if currency := os.Getenv("DEFAULT_CURRENCY"); currency != "" {
+		return strings.ToUpper(currency)
+	}
+	return "USD"
+}
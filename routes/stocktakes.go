@@ -0,0 +1,545 @@
+// routes/stocktakes.go
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/events"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StocktakeRoutes sets up the routes for stocktake (cycle count) sessions
+func StocktakeRoutes(router *gin.Engine) {
+	stocktakeRoutes := router.Group("/stocktakes")
+	stocktakeRoutes.Use(middleware.AuthMiddleware())
+	{
+		stocktakeRoutes.POST("/", CreateStocktake())
+		stocktakeRoutes.GET("/", GetStocktakes())
+		stocktakeRoutes.GET("/:session_id", GetStocktake())
+		stocktakeRoutes.POST("/:session_id/counters", AddStocktakeCounter())
+		stocktakeRoutes.POST("/:session_id/counts", SubmitStocktakeCount())
+		stocktakeRoutes.GET("/:session_id/variances", GetStocktakeVariances())
+		stocktakeRoutes.POST("/:session_id/approve", ApproveStocktake())
+		stocktakeRoutes.POST("/:session_id/cancel", CancelStocktake())
+	}
+}
+
+// CreateStocktake opens a count session for a location and its sublocations,
+// snapshotting the expected quantity of every item stored there
+func CreateStocktake() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var sessionData struct {
+			LocationID uint   `json:"location_id" binding:"required"`
+			Name       string `json:"name"`
+			Notes      string `json:"notes"`
+		}
+		if err := c.ShouldBindJSON(&sessionData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		DB := db.GetDB()
+		locationIDs, err := locationSubtreeIDs(DB, userID, sessionData.LocationID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read locations: " + err.Error()})
+			return
+		}
+		if len(locationIDs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Location not found"})
+			return
+		}
+
+		session := models.StocktakeSession{
+			UserID:     userID,
+			LocationID: sessionData.LocationID,
+			Name:       sessionData.Name,
+			Notes:      sessionData.Notes,
+			Status:     models.StocktakeOpen,
+		}
+		if session.Name == "" {
+			session.Name = "Stocktake " + time.Now().Format("2006-01-02")
+		}
+
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit(clause.Associations).Create(&session).Error; err != nil {
+				return err
+			}
+
+			var items []models.Item
+			if err := tx.Where("user_id = ? AND location_id IN ?", userID, locationIDs).Order("name").Find(&items).Error; err != nil {
+				return err
+			}
+			for _, item := range items {
+				session.Lines = append(session.Lines, models.StocktakeLine{
+					SessionID:        session.ID,
+					ItemID:           item.ID,
+					ItemName:         item.Name,
+					LocationID:       item.LocationID,
+					ExpectedQuantity: item.Quantity,
+				})
+			}
+			if len(session.Lines) == 0 {
+				return nil
+			}
+			return tx.Omit("Item").Create(&session.Lines).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open stocktake: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"stocktake": session})
+	}
+}
+
+// GetStocktakes lists the sessions the user owns or has been invited to count
+func GetStocktakes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		DB := db.GetDB()
+		query := DB.Where("(user_id = ? OR id IN (SELECT session_id FROM stocktake_counters WHERE user_id = ?))", userID, userID)
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+
+		var sessions []models.StocktakeSession
+		if result := query.Order("created_at DESC").Find(&sessions); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve stocktakes: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"stocktakes": sessions})
+	}
+}
+
+// GetStocktake retrieves a session with its lines and counters
+func GetStocktake() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, ok := findStocktakeSession(c, false)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"stocktake": session})
+	}
+}
+
+// AddStocktakeCounter invites another user (by email) to submit counts in an open session.
+// The response doesn't say whether the address belongs to an account.
+func AddStocktakeCounter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var counterData struct {
+			Email string `json:"email" binding:"required,email"`
+		}
+		if err := c.ShouldBindJSON(&counterData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		session, ok := findStocktakeSession(c, true)
+		if !ok {
+			return
+		}
+		if session.Status != models.StocktakeOpen {
+			c.JSON(http.StatusConflict, gin.H{"error": "Stocktake is not open"})
+			return
+		}
+
+		email := strings.ToLower(strings.TrimSpace(counterData.Email))
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			// Lock the session so the same address isn't invited twice at once
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.StocktakeSession{}, session.ID).Error; err != nil {
+				return err
+			}
+			var count int64
+			if err := tx.Model(&models.StocktakeCounter{}).Where("session_id = ? AND LOWER(email) = ?", session.ID, email).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+
+			// Verified accounts can start counting straight away; anyone else once they verify the address
+			counter := models.StocktakeCounter{SessionID: session.ID, Email: email}
+			var user models.User
+			result := tx.Where("LOWER(email) = ? AND email_verified_at IS NOT NULL", email).First(&user)
+			if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+				return result.Error
+			}
+			if result.Error == nil {
+				counter.UserID = &user.ID
+			}
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&counter).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add counter: " + err.Error()})
+			return
+		}
+
+		// The same answer whether or not the address has an account
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":    "Invitation recorded. The account with this verified email can now submit counts.",
+			"session_id": session.ID,
+			"email":      email,
+		})
+	}
+}
+
+// SubmitStocktakeCount records a count for an item identified by item_id or barcode.
+// mode=add (the default, e.g. one barcode scan) adds to the running count; mode=set replaces it.
+func SubmitStocktakeCount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var countData struct {
+			ItemID   uint   `json:"item_id"`
+			Barcode  string `json:"barcode"`
+			Quantity *int   `json:"quantity"`
+			Mode     string `json:"mode" binding:"omitempty,oneof=add set"`
+		}
+		if err := c.ShouldBindJSON(&countData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+		if countData.ItemID == 0 && countData.Barcode == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "item_id or barcode is required"})
+			return
+		}
+		if countData.Mode == "" {
+			countData.Mode = "add"
+		}
+		quantity := 1
+		if countData.Quantity != nil {
+			quantity = *countData.Quantity
+		}
+		if quantity < 0 || (countData.Mode == "add" && quantity == 0) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quantity"})
+			return
+		}
+
+		userID := middleware.GetUserID(c)
+		session, ok := findStocktakeSession(c, false)
+		if !ok {
+			return
+		}
+		if session.Status != models.StocktakeOpen {
+			c.JSON(http.StatusConflict, gin.H{"error": "Stocktake is not open"})
+			return
+		}
+
+		DB := db.GetDB()
+		var line models.StocktakeLine
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			query := tx.Where("stocktake_lines.session_id = ?", session.ID)
+			if countData.ItemID != 0 {
+				query = query.Where("stocktake_lines.item_id = ?", countData.ItemID)
+			} else {
+				query = query.Joins("JOIN items ON items.id = stocktake_lines.item_id").Where("items.barcode = ?", countData.Barcode)
+			}
+			if err := query.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "stocktake_lines"}}).First(&line).Error; err != nil {
+				return err
+			}
+
+			counted := quantity
+			if countData.Mode == "add" && line.CountedQuantity != nil {
+				counted += *line.CountedQuantity
+			}
+			now := time.Now()
+			line.CountedQuantity = &counted
+			line.CountedAt = &now
+			if err := tx.Model(&line).Updates(map[string]interface{}{"counted_quantity": counted, "counted_at": now}).Error; err != nil {
+				return err
+			}
+
+			return tx.Create(&models.StocktakeCount{
+				SessionID: session.ID,
+				LineID:    line.ID,
+				UserID:    userID,
+				Quantity:  quantity,
+				Mode:      countData.Mode,
+			}).Error
+		})
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item is not part of this stocktake"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record count: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"line": line})
+	}
+}
+
+// stocktakeVariance is the difference between expected and counted stock for one line
+type stocktakeVariance struct {
+	LineID           uint    `json:"line_id"`
+	ItemID           uint    `json:"item_id"`
+	ItemName         string  `json:"item_name"`
+	ExpectedQuantity int     `json:"expected_quantity"`
+	CountedQuantity  *int    `json:"counted_quantity"`
+	Variance         int     `json:"variance"`
+	ValueImpact      float64 `json:"value_impact"` // variance at the item's purchase price
+	Currency         string  `json:"currency"`
+}
+
+// GetStocktakeVariances reports counted lines that differ from the snapshot, plus uncounted lines
+func GetStocktakeVariances() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, ok := findStocktakeSession(c, false)
+		if !ok {
+			return
+		}
+
+		itemIDs := make([]uint, 0, len(session.Lines))
+		for _, line := range session.Lines {
+			itemIDs = append(itemIDs, line.ItemID)
+		}
+		items := make(map[uint]models.Item)
+		if len(itemIDs) > 0 {
+			var found []models.Item
+			DB := db.GetDB()
+			if result := DB.Where("id IN ?", itemIDs).Find(&found); result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve items: " + result.Error.Error()})
+				return
+			}
+			for _, item := range found {
+				items[item.ID] = item
+			}
+		}
+
+		variances := []stocktakeVariance{}
+		uncounted := []models.StocktakeLine{}
+		counted := 0
+		for _, line := range session.Lines {
+			if line.CountedQuantity == nil {
+				uncounted = append(uncounted, line)
+				continue
+			}
+			counted++
+			difference := *line.CountedQuantity - line.ExpectedQuantity
+			if difference == 0 {
+				continue
+			}
+			item := items[line.ItemID]
+			variances = append(variances, stocktakeVariance{
+				LineID:           line.ID,
+				ItemID:           line.ItemID,
+				ItemName:         line.ItemName,
+				ExpectedQuantity: line.ExpectedQuantity,
+				CountedQuantity:  line.CountedQuantity,
+				Variance:         difference,
+				ValueImpact:      float64(difference) * item.PurchasePrice,
+				Currency:         itemCurrency(item),
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"session_id":    session.ID,
+			"status":        session.Status,
+			"total_lines":   len(session.Lines),
+			"counted_lines": counted,
+			"variances":     variances,
+			"uncounted":     uncounted,
+		})
+	}
+}
+
+// ApproveStocktake closes a session and posts a stocktake adjustment for every counted
+// line that differs from its snapshot. Movements made while counting are preserved because
+// only the difference is applied. Set zero_uncounted to treat uncounted items as missing.
+// Nothing is applied if a line would take stock below zero; those lines are returned with 409.
+func ApproveStocktake() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var approveData struct {
+			ZeroUncounted bool `json:"zero_uncounted"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&approveData); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+				return
+			}
+		}
+
+		session, ok := findStocktakeSession(c, true)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		reference := fmt.Sprintf("stocktake:%d", session.ID)
+		var adjusted []models.Item
+		var shortfalls []gin.H
+		var outbox events.Outbox
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			// Lock the session so it can only be approved once
+			var current models.StocktakeSession
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, session.ID).Error; err != nil {
+				return err
+			}
+			if current.Status != models.StocktakeOpen {
+				return errStocktakeClosed
+			}
+
+			for _, line := range session.Lines {
+				counted := 0
+				if line.CountedQuantity != nil {
+					counted = *line.CountedQuantity
+				} else if !approveData.ZeroUncounted {
+					continue
+				}
+				delta := counted - line.ExpectedQuantity
+				if delta == 0 {
+					continue
+				}
+
+				var item models.Item
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", line.ItemID, session.UserID).First(&item).Error; err != nil {
+					if err == gorm.ErrRecordNotFound {
+						continue // deleted while counting
+					}
+					return err
+				}
+				// Reserved stock must stay covered (it is never negative), or reservations couldn't be fulfilled
+				if item.Quantity+delta < item.ReservedQuantity {
+					shortfalls = append(shortfalls, gin.H{
+						"line_id":           line.ID,
+						"item_id":           item.ID,
+						"item_name":         line.ItemName,
+						"expected_quantity": line.ExpectedQuantity,
+						"counted_quantity":  counted,
+						"current_quantity":  item.Quantity,
+						"reserved_quantity": item.ReservedQuantity,
+					})
+					continue
+				}
+				if len(shortfalls) > 0 {
+					continue
+				}
+				if _, err := adjustItemStock(tx, &item, delta, models.MovementStocktake, reference); err != nil {
+					return err
+				}
+				adjusted = append(adjusted, item)
+			}
+			if len(shortfalls) > 0 {
+				return errStocktakeShortfall
+			}
+
+			now := time.Now()
+			session.Status = models.StocktakeApproved
+			session.ApprovedAt = &now
+			if err := tx.Model(&current).Updates(map[string]interface{}{"status": session.Status, "approved_at": now}).Error; err != nil {
+				return err
+			}
+			for _, item := range adjusted {
+				if err := outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionUpdated, item); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err == errStocktakeClosed {
+			c.JSON(http.StatusConflict, gin.H{"error": "Stocktake is not open"})
+			return
+		}
+		if err == errStocktakeShortfall {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Some counts would take stock below zero, or below what is reserved, because it was used or reserved since the stocktake opened. Recount these lines, or release the reservations, and approve again.",
+				"lines": shortfalls,
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve stocktake: " + err.Error()})
+			return
+		}
+		outbox.Broadcast()
+
+		c.JSON(http.StatusOK, gin.H{"stocktake": session, "adjusted_items": len(adjusted)})
+	}
+}
+
+// CancelStocktake abandons an open session without changing any stock
+func CancelStocktake() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, ok := findStocktakeSession(c, true)
+		if !ok {
+			return
+		}
+		if session.Status != models.StocktakeOpen {
+			c.JSON(http.StatusConflict, gin.H{"error": "Stocktake is not open"})
+			return
+		}
+
+		DB := db.GetDB()
+		session.Status = models.StocktakeCancelled
+		if result := DB.Model(&session).Update("status", session.Status); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel stocktake: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"stocktake": session})
+	}
+}
+
+// errStocktakeClosed is returned when approving a session that is no longer open
+var errStocktakeClosed = fmt.Errorf("stocktake is not open")
+
+// errStocktakeShortfall is returned when applying the counted variances would make stock
+// negative or leave less than is reserved
+var errStocktakeShortfall = fmt.Errorf("stocktake would leave less stock than is reserved")
+
+// errItemInStocktake is returned when deleting an item an open stocktake is counting
+var errItemInStocktake = fmt.Errorf("item is in an open stocktake")
+
+// findStocktakeSession loads the session named in the URL with its lines and counters.
+// Counters may read it and submit counts; ownerOnly restricts access to the owner.
+// It writes the error response itself and reports whether the handler should continue.
+func findStocktakeSession(c *gin.Context, ownerOnly bool) (models.StocktakeSession, bool) {
+	var session models.StocktakeSession
+
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return session, false
+	}
+
+	DB := db.GetDB()
+	query := DB.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("item_name, id")
+	}).Preload("Counters").Where("id = ?", c.Param("session_id"))
+	if ownerOnly {
+		query = query.Where("user_id = ?", userID)
+	} else {
+		query = query.Where("(user_id = ? OR id IN (SELECT session_id FROM stocktake_counters WHERE user_id = ?))", userID, userID)
+	}
+
+	if result := query.First(&session); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Stocktake not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve stocktake: " + result.Error.Error()})
+		}
+		return session, false
+	}
+	return session, true
+}
@@ -0,0 +1,227 @@
+// routes/suppliers.go
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/currency"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// SupplierRoutes sets up the routes for managing suppliers
+func SupplierRoutes(router *gin.Engine) {
+	supplierRoutes := router.Group("/suppliers")
+	supplierRoutes.Use(middleware.AuthMiddleware())
+	{
+		supplierRoutes.POST("/", CreateSupplier())
+		supplierRoutes.GET("/", GetSuppliers())
+		supplierRoutes.GET("/:supplier_id", GetSupplier())
+		supplierRoutes.PUT("/:supplier_id", UpdateSupplier())
+		supplierRoutes.DELETE("/:supplier_id", DeleteSupplier())
+	}
+}
+
+// supplierRequest is the payload for creating or updating a supplier
+type supplierRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Email    string `json:"email" binding:"omitempty,email"`
+	Phone    string `json:"phone"`
+	Website  string `json:"website" binding:"omitempty,url"`
+	Currency string `json:"currency"`
+	Notes    string `json:"notes"`
+}
+
+// CreateSupplier adds a supplier
+func CreateSupplier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var supplierData supplierRequest
+		if err := c.ShouldBindJSON(&supplierData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		supplier := models.Supplier{UserID: userID}
+		if err := applySupplierRequest(&supplier, supplierData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency: " + err.Error()})
+			return
+		}
+
+		DB := db.GetDB()
+		if result := DB.Create(&supplier); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create supplier: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"supplier": supplier})
+	}
+}
+
+// GetSuppliers lists the authenticated user's suppliers
+func GetSuppliers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		var suppliers []models.Supplier
+		DB := db.GetDB()
+		if result := DB.Where("user_id = ?", userID).Order("name").Find(&suppliers); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve suppliers: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"suppliers": suppliers})
+	}
+}
+
+// GetSupplier retrieves a supplier along with its open purchase orders
+func GetSupplier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		supplier, ok := findUserSupplier(c, c.Param("supplier_id"))
+		if !ok {
+			return
+		}
+
+		var orders []models.PurchaseOrder
+		DB := db.GetDB()
+		if result := DB.Where("supplier_id = ? AND status IN ?", supplier.ID, []string{
+			models.PurchaseOrderDraft, models.PurchaseOrderOrdered, models.PurchaseOrderPartiallyReceived,
+		}).Order("created_at DESC").Find(&orders); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve purchase orders: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"supplier": supplier, "open_orders": orders})
+	}
+}
+
+// UpdateSupplier updates a supplier's details
+func UpdateSupplier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var supplierData supplierRequest
+		if err := c.ShouldBindJSON(&supplierData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		supplier, ok := findUserSupplier(c, c.Param("supplier_id"))
+		if !ok {
+			return
+		}
+		if err := applySupplierRequest(&supplier, supplierData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency: " + err.Error()})
+			return
+		}
+
+		DB := db.GetDB()
+		if result := DB.Save(&supplier); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update supplier: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"supplier": supplier})
+	}
+}
+
+// DeleteSupplier removes a supplier that has no purchase orders
+func DeleteSupplier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		supplier, ok := findUserSupplier(c, c.Param("supplier_id"))
+		if !ok {
+			return
+		}
+
+		// Keep purchasing history intact
+		var count int64
+		DB := db.GetDB()
+		if result := DB.Model(&models.PurchaseOrder{}).Where("supplier_id = ?", supplier.ID).Count(&count); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check purchase orders: " + result.Error.Error()})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete a supplier with purchase orders"})
+			return
+		}
+
+		// Items fall back to their free-text preferred supplier
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Item{}).Where("supplier_id = ?", supplier.ID).Update("supplier_id", nil).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&supplier).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete supplier: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Supplier deleted successfully"})
+	}
+}
+
+// userOwnsSupplier reports whether an optional supplier ID belongs to the user
+func userOwnsSupplier(DB *gorm.DB, userID uint, supplierID *uint) bool {
+	if supplierID == nil {
+		return true
+	}
+	var count int64
+	if result := DB.Model(&models.Supplier{}).Where("id = ? AND user_id = ?", *supplierID, userID).Count(&count); result.Error != nil {
+		return false
+	}
+	return count > 0
+}
+
+// applySupplierRequest copies a supplier payload onto a supplier, checking its currency
+func applySupplierRequest(supplier *models.Supplier, supplierData supplierRequest) error {
+	code := defaultCurrency()
+	if supplierData.Currency != "" {
+		code = supplierData.Currency
+	}
+	code, err := currency.Normalize(code)
+	if err != nil {
+		return err
+	}
+
+	supplier.Name = supplierData.Name
+	supplier.Email = supplierData.Email
+	supplier.Phone = supplierData.Phone
+	supplier.Website = supplierData.Website
+	supplier.Currency = code
+	supplier.Notes = supplierData.Notes
+	return nil
+}
+
+// findUserSupplier loads a supplier if it belongs to the authenticated user.
+// It writes the error response itself and reports whether the handler should continue.
+func findUserSupplier(c *gin.Context, supplierID interface{}) (models.Supplier, bool) {
+	var supplier models.Supplier
+
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return supplier, false
+	}
+
+	DB := db.GetDB()
+	if result := DB.Where("id = ? AND user_id = ?", supplierID, userID).First(&supplier); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Supplier not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve supplier: " + result.Error.Error()})
+		}
+		return supplier, false
+	}
+	return supplier, true
+}
@@ -0,0 +1,487 @@
+// routes/sync.go
+package routes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/events"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Per-mutation outcomes reported by PushSync
+const (
+	syncApplied  = "applied"
+	syncConflict = "conflict"
+	syncRejected = "rejected"
+)
+
+// syncCursorPrefix versions the opaque cursor so its format can change later
+const syncCursorPrefix = "v1:"
+
+// maxSyncMutations caps the size of a single upload batch
+const maxSyncMutations = 500
+
+// errSyncConflict is returned when an uploaded change was made to an older version of a record
+var errSyncConflict = fmt.Errorf("record changed since the client's version")
+
+// SyncRoutes sets up the delta sync routes used by offline-first clients
+func SyncRoutes(router *gin.Engine) {
+	syncRoutes := router.Group("/sync")
+	syncRoutes.Use(middleware.AuthMiddleware())
+	{
+		syncRoutes.GET("/", PullSync())
+		syncRoutes.POST("/", PushSync())
+	}
+}
+
+// syncMutation is a single client-side change uploaded to PushSync
+type syncMutation struct {
+	ClientID      string          `json:"client_id"`   // echoed back so the client can match results
+	EntityType    string          `json:"entity_type"` // "item" or "location"
+	Action        string          `json:"action"`      // "create", "update" or "delete"
+	ID            uint            `json:"id"`          // server ID (update/delete only)
+	BaseUpdatedAt *time.Time      `json:"base_updated_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// syncResult is the resolved state returned for each uploaded mutation
+type syncResult struct {
+	ClientID   string      `json:"client_id,omitempty"`
+	EntityType string      `json:"entity_type"`
+	ID         uint        `json:"id,omitempty"`
+	Status     string      `json:"status"`
+	Error      string      `json:"error,omitempty"`
+	Record     interface{} `json:"record,omitempty"` // current server state (nil once deleted)
+}
+
+// PullSync returns every item and location changed or deleted since the given cursor.
+// Without a cursor it returns a full snapshot of what the user can see. The cursor is an
+// event log position; event IDs become visible in order, so nothing committed late is skipped.
+// A cursor older than the retained log gets 410 and the client has to pull a full snapshot.
+func PullSync() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		DB := db.GetDB()
+		since := c.Query("since")
+
+		if since == "" {
+			// Read the log position first so changes made during the snapshot are picked up next time
+			latest, err := events.LatestID(DB)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read event log: " + err.Error()})
+				return
+			}
+
+			var items []models.Item
+			if result := visibleItems(DB, userID).Find(&items); result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve items: " + result.Error.Error()})
+				return
+			}
+
+			var locations []models.Location
+			if result := visibleLocations(DB, userID).Find(&locations); result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve locations: " + result.Error.Error()})
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{
+				"items":             items,
+				"locations":         locations,
+				"deleted_items":     []uint{},
+				"deleted_locations": []uint{},
+				"cursor":            encodeSyncCursor(latest),
+				"full":              true,
+			})
+			return
+		}
+
+		lastID, err := decodeSyncCursor(since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sync cursor"})
+			return
+		}
+
+		changes, err := events.Since(DB, userID, lastID)
+		if err == events.ErrResyncRequired {
+			c.JSON(http.StatusGone, gin.H{"error": "Sync cursor has expired, pull again without a cursor", "resync": true})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read event log: " + err.Error()})
+			return
+		}
+
+		// Collapse the log to the latest action per record
+		cursor := lastID
+		itemActions := make(map[uint]string)
+		locationActions := make(map[uint]string)
+		for _, event := range changes {
+			switch event.EntityType {
+			case events.EntityItem:
+				itemActions[event.EntityID] = event.Action
+			case events.EntityLocation:
+				locationActions[event.EntityID] = event.Action
+			}
+			cursor = event.ID
+		}
+
+		changedItems, deletedItems := splitSyncActions(itemActions)
+		changedLocations, deletedLocations := splitSyncActions(locationActions)
+
+		items := []models.Item{}
+		if len(changedItems) > 0 {
+			if result := visibleItems(DB, userID).Where("id IN ?", changedItems).Find(&items); result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve items: " + result.Error.Error()})
+				return
+			}
+		}
+
+		locations := []models.Location{}
+		if len(changedLocations) > 0 {
+			if result := visibleLocations(DB, userID).Where("id IN ?", changedLocations).Find(&locations); result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve locations: " + result.Error.Error()})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"items":             items,
+			"locations":         locations,
+			"deleted_items":     deletedItems,
+			"deleted_locations": deletedLocations,
+			"cursor":            encodeSyncCursor(cursor),
+			"full":              false,
+		})
+	}
+}
+
+// PushSync applies a batch of client mutations, detecting conflicts per record using updated_at
+func PushSync() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		var request struct {
+			Mutations []syncMutation `json:"mutations" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+		if len(request.Mutations) > maxSyncMutations {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Too many mutations (max %d per batch)", maxSyncMutations)})
+			return
+		}
+
+		DB := db.GetDB()
+		results := make([]syncResult, 0, len(request.Mutations))
+		for _, mutation := range request.Mutations {
+			var result syncResult
+			switch mutation.EntityType {
+			case events.EntityItem:
+				result = applyItemMutation(DB, userID, mutation)
+			case events.EntityLocation:
+				result = applyLocationMutation(DB, userID, mutation)
+			default:
+				result = syncResult{EntityType: mutation.EntityType, ID: mutation.ID, Status: syncRejected, Error: "Unknown entity type"}
+			}
+			result.ClientID = mutation.ClientID
+			results = append(results, result)
+		}
+
+		// No cursor is returned: clients pull with their previous cursor so changes
+		// made elsewhere in the meantime are not skipped
+		c.JSON(http.StatusOK, gin.H{"results": results})
+	}
+}
+
+// applyItemMutation applies one uploaded item change owned by the user
+func applyItemMutation(DB *gorm.DB, userID uint, mutation syncMutation) syncResult {
+	result := syncResult{EntityType: events.EntityItem, ID: mutation.ID}
+
+	if mutation.Action == "create" {
+		var item models.Item
+		if err := decodeSyncData(mutation.Data, &item); err != nil {
+			result.Status, result.Error = syncRejected, "Invalid item data: "+err.Error()
+			return result
+		}
+		item.ID = 0
+		item.UserID = userID
+		item.ReceiptUrl = ""
+		item.ReservedQuantity = 0
+		var outbox events.Outbox
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := validateItem(tx, userID, &item); err != nil {
+				return err
+			}
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+			if err := recordStockChange(tx, item, 0, models.MovementOpening, "sync"); err != nil {
+				return err
+			}
+			return outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionCreated, item)
+		})
+		if validationErr, ok := err.(*itemValidationError); ok {
+			result.Status, result.Error = syncRejected, validationErr.Error()
+			return result
+		}
+		if err != nil {
+			result.Status, result.Error = syncRejected, "Failed to create item: "+err.Error()
+			return result
+		}
+		outbox.Broadcast()
+		result.ID, result.Status, result.Record = item.ID, syncApplied, item
+		return result
+	}
+
+	if mutation.Action != "update" && mutation.Action != "delete" {
+		result.Status, result.Error = syncRejected, "Unknown action"
+		return result
+	}
+
+	var item models.Item
+	var outbox events.Outbox
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the row so two uploads against the same version can't both pass the conflict check
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", mutation.ID, userID).First(&item).Error; err != nil {
+			return err
+		}
+		// The client edited an older version than the server has: return the server state instead
+		if syncConflicts(item.UpdatedAt, mutation.BaseUpdatedAt) {
+			return errSyncConflict
+		}
+
+		if mutation.Action == "delete" {
+			// Published first, while the shares that say who else sees it still exist
+			if err := outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionDeleted, item); err != nil {
+				return err
+			}
+			return deleteItem(tx, item)
+		}
+
+		stored := item
+		if err := decodeSyncData(mutation.Data, &item); err != nil {
+			return &itemValidationError{"Invalid item data: " + err.Error()}
+		}
+		return updateItem(tx, &outbox, stored, &item, "sync")
+	})
+	if err == gorm.ErrRecordNotFound {
+		result.Status, result.Error = syncRejected, "Item not found"
+		return result
+	}
+	if err == errSyncConflict {
+		result.Status, result.Record = syncConflict, item
+		return result
+	}
+	if validationErr, ok := err.(*itemValidationError); ok {
+		result.Status, result.Error = syncRejected, validationErr.Error()
+		return result
+	}
+	if err == errBelowReserved {
+		result.Status, result.Error = syncRejected, fmt.Sprintf("Quantity can't be lower than the %d units reserved", item.ReservedQuantity)
+		return result
+	}
+	if message, refused := itemDeleteConflicts[err]; refused {
+		result.Status, result.Error = syncRejected, message
+		return result
+	}
+	if err != nil {
+		result.Status, result.Error = syncRejected, "Failed to "+mutation.Action+" item: "+err.Error()
+		return result
+	}
+	outbox.Broadcast()
+
+	if mutation.Action == "delete" {
+		removeReceiptFile(item)
+		result.Status = syncApplied
+		return result
+	}
+	result.Status, result.Record = syncApplied, item
+	return result
+}
+
+// applyLocationMutation applies one uploaded location change owned by the user
+func applyLocationMutation(DB *gorm.DB, userID uint, mutation syncMutation) syncResult {
+	result := syncResult{EntityType: events.EntityLocation, ID: mutation.ID}
+
+	var data models.Location
+	if mutation.Action != "delete" {
+		if err := decodeSyncData(mutation.Data, &data); err != nil {
+			result.Status, result.Error = syncRejected, "Invalid location data: "+err.Error()
+			return result
+		}
+	}
+
+	if mutation.Action == "create" {
+		location := models.Location{
+			Name:        data.Name,
+			Description: data.Description,
+			ImageUrl:    data.ImageUrl,
+			UserID:      userID,
+			ParentID:    data.ParentID,
+		}
+		if location.ParentID != nil && !userCanUseLocation(DB, userID, *location.ParentID) {
+			result.Status, result.Error = syncRejected, "Parent location not found"
+			return result
+		}
+		var outbox events.Outbox
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&location).Error; err != nil {
+				return err
+			}
+			return outbox.Publish(tx, location.UserID, events.EntityLocation, location.ID, events.ActionCreated, location)
+		})
+		if err != nil {
+			result.Status, result.Error = syncRejected, "Failed to create location: "+err.Error()
+			return result
+		}
+		outbox.Broadcast()
+		result.ID, result.Status, result.Record = location.ID, syncApplied, location
+		return result
+	}
+
+	if mutation.Action != "update" && mutation.Action != "delete" {
+		result.Status, result.Error = syncRejected, "Unknown action"
+		return result
+	}
+	if mutation.Action == "update" && data.ParentID != nil && !userCanUseLocation(DB, userID, *data.ParentID) {
+		result.Status, result.Error = syncRejected, "Parent location not found"
+		return result
+	}
+
+	// Only owned locations can be changed; public ones are read-only. The row is locked so two
+	// uploads against the same version can't both pass the conflict check.
+	var location models.Location
+	var outbox events.Outbox
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", mutation.ID, userID).First(&location).Error; err != nil {
+			return err
+		}
+		if syncConflicts(location.UpdatedAt, mutation.BaseUpdatedAt) {
+			return errSyncConflict
+		}
+
+		if mutation.Action == "delete" {
+			// Published first, while the shares that say who else sees it still exist
+			if err := outbox.Publish(tx, location.UserID, events.EntityLocation, location.ID, events.ActionDeleted, location); err != nil {
+				return err
+			}
+			return deleteLocation(tx, location)
+		}
+
+		location.Name = data.Name
+		location.Description = data.Description
+		location.ImageUrl = data.ImageUrl
+		location.ParentID = data.ParentID
+		if err := checkLocationMove(tx, userID, location); err != nil {
+			return err
+		}
+		if err := tx.Save(&location).Error; err != nil {
+			return err
+		}
+		return outbox.Publish(tx, location.UserID, events.EntityLocation, location.ID, events.ActionUpdated, location)
+	})
+	if err == gorm.ErrRecordNotFound {
+		result.Status, result.Error = syncRejected, "Location not found or you don't have permission to change it"
+		return result
+	}
+	if err == errSyncConflict {
+		result.Status, result.Record = syncConflict, location
+		return result
+	}
+	if err == errLocationCycle {
+		result.Status, result.Error = syncRejected, "A location cannot be moved inside itself or its sublocations"
+		return result
+	}
+	if message, refused := locationDeleteConflicts[err]; refused {
+		result.Status, result.Error, result.Record = syncRejected, message, location
+		return result
+	}
+	if err != nil {
+		result.Status, result.Error = syncRejected, "Failed to "+mutation.Action+" location: "+err.Error()
+		return result
+	}
+	outbox.Broadcast()
+
+	if mutation.Action == "delete" {
+		result.Status = syncApplied
+		return result
+	}
+	result.Status, result.Record = syncApplied, location
+	return result
+}
+
+// decodeSyncData decodes an uploaded record and applies the same binding validation as the
+// REST handlers, e.g. rejecting negative quantities
+func decodeSyncData(data json.RawMessage, record interface{}) error {
+	if err := json.Unmarshal(data, record); err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(record)
+}
+
+// syncConflicts reports whether the server copy changed after the version the client edited.
+// Timestamps are compared at microsecond precision, which is what Postgres stores.
+func syncConflicts(serverUpdatedAt time.Time, baseUpdatedAt *time.Time) bool {
+	if baseUpdatedAt == nil {
+		return true
+	}
+	return !serverUpdatedAt.Truncate(time.Microsecond).Equal(baseUpdatedAt.Truncate(time.Microsecond))
+}
+
+// splitSyncActions separates records whose latest action is a delete from the ones that still exist
+func splitSyncActions(actions map[uint]string) ([]uint, []uint) {
+	changed := []uint{}
+	deleted := []uint{}
+	for id, action := range actions {
+		if action == events.ActionDeleted {
+			deleted = append(deleted, id)
+		} else {
+			changed = append(changed, id)
+		}
+	}
+	return changed, deleted
+}
+
+// encodeSyncCursor wraps an event log position in an opaque token
+func encodeSyncCursor(eventID uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(syncCursorPrefix + strconv.FormatUint(uint64(eventID), 10)))
+}
+
+// decodeSyncCursor extracts the event log position from a cursor token
+func decodeSyncCursor(cursor string) (uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	value := string(raw)
+	if !strings.HasPrefix(value, syncCursorPrefix) {
+		return 0, fmt.Errorf("unsupported cursor version")
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(value, syncCursorPrefix), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,256 @@
+// routes/tags.go
+package routes
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/events"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TagRoutes sets up the routes for tags and tagging items
+func TagRoutes(router *gin.Engine) {
+	tagRoutes := router.Group("/tags")
+	tagRoutes.Use(middleware.AuthMiddleware())
+	{
+		tagRoutes.POST("/", CreateTag())
+		tagRoutes.GET("/", GetTags())
+		tagRoutes.PUT("/:tag_id", UpdateTag())
+		tagRoutes.DELETE("/:tag_id", DeleteTag())
+	}
+
+	itemTagRoutes := router.Group("/items")
+	itemTagRoutes.Use(middleware.AuthMiddleware())
+	{
+		itemTagRoutes.PUT("/:item_id/tags", SetItemTags())
+	}
+}
+
+// CreateTag adds a tag to the user's vocabulary
+func CreateTag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var tagData struct {
+			Name string `json:"name" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&tagData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		DB := db.GetDB()
+		tags, err := findOrCreateTags(DB, userID, []string{tagData.Name})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tag: " + err.Error()})
+			return
+		}
+		if len(tags) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tag name cannot be blank"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"tag": tags[0]})
+	}
+}
+
+// GetTags lists the user's tags with how many items carry each one
+func GetTags() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		var tags []struct {
+			ID        uint   `json:"id"`
+			Name      string `json:"name"`
+			ItemCount int64  `json:"item_count"`
+		}
+		DB := db.GetDB()
+		if result := DB.Model(&models.Tag{}).
+			Select("tags.id, tags.name, COUNT(item_tags.item_id) AS item_count").
+			Joins("LEFT JOIN item_tags ON item_tags.tag_id = tags.id").
+			Where("tags.user_id = ?", userID).
+			Group("tags.id, tags.name").
+			Order("tags.name").
+			Scan(&tags); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tags: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"tags": tags})
+	}
+}
+
+// UpdateTag renames a tag
+func UpdateTag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var tagData struct {
+			Name string `json:"name" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&tagData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+		name := normalizeTagName(tagData.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tag name cannot be blank"})
+			return
+		}
+
+		tag, ok := findUserTag(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		var existing models.Tag
+		if result := DB.Where("user_id = ? AND name = ? AND id <> ?", tag.UserID, name, tag.ID).First(&existing); result.Error == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "A tag with this name already exists"})
+			return
+		}
+
+		tag.Name = name
+		if result := DB.Save(&tag); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag: " + result.Error.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"tag": tag})
+	}
+}
+
+// DeleteTag removes a tag from the user's vocabulary and from every item carrying it
+func DeleteTag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tag, ok := findUserTag(c)
+		if !ok {
+			return
+		}
+
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("DELETE FROM item_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&tag).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+	}
+}
+
+// SetItemTags replaces an item's tags with the given names, creating any new tags
+func SetItemTags() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var tagData struct {
+			Tags []string `json:"tags"`
+		}
+		if err := c.ShouldBindJSON(&tagData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		item, ok := findUserItem(c)
+		if !ok {
+			return
+		}
+
+		var outbox events.Outbox
+		DB := db.GetDB()
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			tags, err := findOrCreateTags(tx, item.UserID, tagData.Tags)
+			if err != nil {
+				return err
+			}
+			item.Tags = tags
+			if err := tx.Model(&item).Omit("Tags.*").Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+			return outbox.Publish(tx, item.UserID, events.EntityItem, item.ID, events.ActionUpdated, item)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item tags: " + err.Error()})
+			return
+		}
+		outbox.Broadcast()
+
+		c.JSON(http.StatusOK, gin.H{"item_id": item.ID, "tags": item.Tags})
+	}
+}
+
+// normalizeTagName trims and lower-cases a tag so "Winter" and "winter " are the same tag
+func normalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// findOrCreateTags resolves tag names to the user's tags, creating the ones that don't exist yet
+func findOrCreateTags(DB *gorm.DB, userID uint, names []string) ([]models.Tag, error) {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, name := range names {
+		name = normalizeTagName(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		unique = append(unique, name)
+	}
+
+	tags := []models.Tag{}
+	if len(unique) == 0 {
+		return tags, nil
+	}
+
+	newTags := make([]models.Tag, 0, len(unique))
+	for _, name := range unique {
+		newTags = append(newTags, models.Tag{UserID: userID, Name: name})
+	}
+	if err := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&newTags).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Where("user_id = ? AND name IN ?", userID, unique).Order("name").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// findUserTag loads the tag named in the URL if it belongs to the authenticated user.
+// It writes the error response itself and reports whether the handler should continue.
+func findUserTag(c *gin.Context) (models.Tag, bool) {
+	var tag models.Tag
+
+	userID := middleware.GetUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+		return tag, false
+	}
+
+	DB := db.GetDB()
+	if result := DB.Where("id = ? AND user_id = ?", c.Param("tag_id"), userID).First(&tag); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tag: " + result.Error.Error()})
+		}
+		return tag, false
+	}
+	return tag, true
+}
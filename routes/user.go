@@ -2,70 +2,76 @@
 package routes
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/mailer"
 	"github.com/sidhant-sriv/inventory-api/middleware"
 	"github.com/sidhant-sriv/inventory-api/models"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/sidhant-sriv/inventory-api/passwords"
+	"gorm.io/gorm"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
-// Example of protecting user routes with authentication
-func UserRoutes(router *gin.Engine) {
-	// Public route
-	router.POST("/users", CreateUser())
-
-	// Protected routes
-	userRoutes := router.Group("/users")
-	userRoutes.Use(middleware.AuthMiddleware())
-	{
-		userRoutes.GET("/:user_id", GetUser())
-		userRoutes.GET("/", GetAllUsers())
-		userRoutes.PUT("/:user_id", UpdateUser())
-		userRoutes.DELETE("/:user_id", DeleteUser())
-	}
-}
-
-// CreateUser handles the creation of a new user
+// CreateUser handles the creation of a new user. Like Register, it answers the same way
+// whether or not the email is already registered.
 func CreateUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var user models.User
-		if err := c.ShouldBindJSON(&user); err != nil {
+		var userRequest struct {
+			Name     string `json:"name" binding:"required"`
+			Email    string `json:"email" binding:"required,email"`
+			Password string `json:"password" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&userRequest); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-
-		// Hash the password
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		if err := passwords.Check(userRequest.Password, userRequest.Email, userRequest.Name); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		user.Password = string(hashedPassword)
 
-		// Create the user in database
 		DB := db.GetDB()
-		if result := DB.Create(&user); result.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user: " + result.Error.Error()})
+		if err := registerUser(DB, userRequest.Name, userRequest.Email, userRequest.Password); err != nil {
+			fmt.Printf("Error creating user: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 			return
 		}
 
-		// Don't return the password in the response
-		user.Password = ""
-		c.JSON(http.StatusCreated, gin.H{"user": user})
+		c.JSON(http.StatusAccepted, gin.H{"message": registrationMessage})
 	}
 }
 
-// GetUser retrieves a user by ID
+// emailChangeMessage is the reply to every email change, whether or not the new address is taken
+const emailChangeMessage = "To finish changing your email, follow the link sent to the new address."
+
+// findAccountUser loads the account a /users/:user_id request is about. Users can only reach
+// their own; administrators can reach anyone's. Other accounts look the same as missing ones.
+func findAccountUser(c *gin.Context) (models.User, bool) {
+	caller, ok := findCurrentUser(c)
+	if !ok {
+		return caller, false
+	}
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err == nil && uint(userID) == caller.ID {
+		return caller, true
+	}
+
+	var user models.User
+	if err != nil || caller.Role != models.RoleAdmin || db.GetDB().First(&user, userID).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return user, false
+	}
+	return user, true
+}
+
+// GetUser retrieves the caller's account, or any account for administrators
 func GetUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userId := c.Param("user_id")
-		var user models.User
-
-		DB := db.GetDB()
-		if result := DB.First(&user, userId); result.Error != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		user, ok := findAccountUser(c)
+		if !ok {
 			return
 		}
 
@@ -75,7 +81,7 @@ func GetUser() gin.HandlerFunc {
 	}
 }
 
-// GetAllUsers retrieves all users with pagination
+// GetAllUsers retrieves all users with pagination (administrators only)
 func GetAllUsers() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Pagination parameters
@@ -114,24 +120,27 @@ func GetAllUsers() gin.HandlerFunc {
 	}
 }
 
-// UpdateUser updates a user's information
+// UpdateUser updates the caller's account, or any account for administrators. A new email
+// address only takes effect once the link sent to it is followed. Changing the password or
+// email signs the account out everywhere except the session that made the change.
 func UpdateUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userId := c.Param("user_id")
-		var user models.User
-
+		user, ok := findAccountUser(c)
+		if !ok {
+			return
+		}
 		DB := db.GetDB()
 
-		// Check if user exists
-		if result := DB.First(&user, userId); result.Error != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-			return
+		// An administrator changing someone else's account keeps none of their sessions
+		keepSessionID := uint(0)
+		if user.ID == middleware.GetUserID(c) {
+			keepSessionID = middleware.GetSessionID(c)
 		}
 
 		// Get update data
 		var updateData struct {
 			Name     string `json:"name"`
-			Email    string `json:"email"`
+			Email    string `json:"email" binding:"omitempty,email"`
 			Password string `json:"password,omitempty"`
 		}
 
@@ -144,55 +153,102 @@ func UpdateUser() gin.HandlerFunc {
 		if updateData.Name != "" {
 			user.Name = updateData.Name
 		}
-
-		if updateData.Email != "" {
-			// Check if email is already taken
-			var existingUser models.User
-			if result := DB.Where("email = ? AND id != ?", updateData.Email, userId).First(&existingUser); result.Error == nil {
-				c.JSON(http.StatusConflict, gin.H{"error": "Email is already taken"})
-				return
-			}
-			user.Email = updateData.Email
-		}
+		emailChanged := updateData.Email != "" && !strings.EqualFold(updateData.Email, user.Email)
 
 		// Update password if provided
 		if updateData.Password != "" {
-			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(updateData.Password), bcrypt.DefaultCost)
+			if err := passwords.Check(updateData.Password, user.Email, user.Name); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			hashedPassword, err := passwords.Hash(updateData.Password)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
 				return
 			}
-			user.Password = string(hashedPassword)
+			user.Password = hashedPassword
 		}
 
 		// Save updated user
-		if result := DB.Save(&user); result.Error != nil {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&user).Error; err != nil {
+				return err
+			}
+			if updateData.Password == "" {
+				return nil
+			}
+			return models.RevokeOtherUserSessions(tx, user.ID, keepSessionID)
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 			return
 		}
 
 		// Don't return the password
 		user.Password = ""
-		c.JSON(http.StatusOK, gin.H{"user": user})
+		if !emailChanged {
+			c.JSON(http.StatusOK, gin.H{"user": user})
+			return
+		}
+
+		// The new address has to be confirmed first. If it already belongs to an account its
+		// owner is told instead; that runs in the background so the reply doesn't reveal which.
+		go requestEmailChange(DB, user, updateData.Email, keepSessionID)
+		c.JSON(http.StatusAccepted, gin.H{"user": user, "message": emailChangeMessage})
 	}
 }
 
-// DeleteUser deletes a user
-func DeleteUser() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		userId := c.Param("user_id")
-		var user models.User
+// requestEmailChange sends a verification link for a new address. VerifyEmail switches the
+// account over when it is followed, signing out every session but the one that asked.
+func requestEmailChange(DB *gorm.DB, user models.User, email string, sessionID uint) {
+	var existing models.User
+	result := DB.Unscoped().Where("LOWER(email) = LOWER(?)", email).First(&existing)
+	if result.Error == nil {
+		if !existing.DeletedAt.Valid {
+			sendExistingAccountNotice(existing)
+		}
+		return
+	}
+	if result.Error != gorm.ErrRecordNotFound {
+		fmt.Printf("Database error during email change lookup: %v\n", result.Error)
+		return
+	}
 
-		DB := db.GetDB()
+	user.Email = email
+	token, err := issueUserToken(DB, user, models.TokenEmailVerification, emailVerificationTTL)
+	if err == nil {
+		err = DB.Model(&models.UserToken{}).Where("token_hash = ?", hashUserToken(token)).Update("session_id", sessionID).Error
+	}
+	if err != nil {
+		fmt.Printf("Error issuing email change token for user ID %d: %v\n", user.ID, err)
+		return
+	}
+	sendEmail(mailer.Message{
+		To:      email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm you want to use this address for your inventory account by opening "+
+			"this link within %s:\n\n%s\n\nUntil you do, your account keeps its current address.\n",
+			user.Name, emailVerificationTTL, accountLink("verify-email", token)),
+	})
+}
 
-		// Check if user exists
-		if result := DB.First(&user, userId); result.Error != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+// DeleteUser deletes the caller's account, or any account for administrators
+func DeleteUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := findAccountUser(c)
+		if !ok {
 			return
 		}
+		DB := db.GetDB()
 
-		// Delete the user (soft delete with GORM)
-		if result := DB.Delete(&user); result.Error != nil {
+		// Delete the user (soft delete with GORM) and sign them out everywhere
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(&user).Error; err != nil {
+				return err
+			}
+			return models.RevokeUserSessions(tx, user.ID)
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 			return
 		}
@@ -0,0 +1,375 @@
+// routes/valuation.go
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sidhant-sriv/inventory-api/currency"
+	"github.com/sidhant-sriv/inventory-api/db"
+	"github.com/sidhant-sriv/inventory-api/middleware"
+	"github.com/sidhant-sriv/inventory-api/models"
+	"gorm.io/gorm"
+)
+
+// Costing methods supported by the valuation report
+const (
+	costingFIFO    = "fifo"
+	costingAverage = "average"
+)
+
+// ValuationRoutes sets up the inventory valuation report routes
+func ValuationRoutes(router *gin.Engine) {
+	valuationRoutes := router.Group("/valuation")
+	valuationRoutes.Use(middleware.AuthMiddleware())
+	{
+		valuationRoutes.GET("/", GetValuation())
+	}
+}
+
+// itemValuation is the on-hand quantity and cost of a single item
+type itemValuation struct {
+	ItemID     uint    `json:"item_id"`
+	Name       string  `json:"name"`
+	LocationID uint    `json:"location_id"`
+	CategoryID *uint   `json:"category_id"`
+	Quantity   int     `json:"quantity"`
+	Value      float64 `json:"value"`
+	Currency   string  `json:"currency"`
+
+	// Set when the value was converted into a reporting currency
+	OriginalValue    float64 `json:"original_value,omitempty"`
+	OriginalCurrency string  `json:"original_currency,omitempty"`
+}
+
+// valuationGroup totals the value of a set of items, per currency
+type valuationGroup struct {
+	ID       *uint              `json:"id"`
+	Name     string             `json:"name"`
+	Quantity int                `json:"quantity"`
+	Totals   map[string]float64 `json:"totals"`
+}
+
+// costLayer is a batch of incoming stock still on hand, used for FIFO costing
+type costLayer struct {
+	quantity int
+	unitCost float64
+}
+
+// GetValuation totals the value of the user's inventory from the stock movement ledger.
+//
+//	method=fifo|average              costing method (default fifo)
+//	as_of=YYYY-MM-DD                 value stock as it stood at the end of that day (default now)
+//	group_by=total|location|category|item
+//	currency=<ISO-4217 code>         convert values at the rates effective on as_of
+//
+// Items are grouped by their current location and category. Without a reporting
+// currency, values are totalled per currency since items may be bought in different currencies.
+func GetValuation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.GetUserID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			return
+		}
+
+		method := c.DefaultQuery("method", costingFIFO)
+		if method != costingFIFO && method != costingAverage {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "method must be fifo or average"})
+			return
+		}
+
+		groupBy := c.DefaultQuery("group_by", "total")
+		if groupBy != "total" && groupBy != "location" && groupBy != "category" && groupBy != "item" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be total, location, category or item"})
+			return
+		}
+
+		asOf, err := parseReportDate(c.Query("as_of"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid as_of date. Use YYYY-MM-DD"})
+			return
+		}
+
+		reportingCurrency := ""
+		if value := c.Query("currency"); value != "" {
+			if reportingCurrency, err = currency.Normalize(value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency: " + err.Error()})
+				return
+			}
+		}
+
+		DB := db.GetDB()
+		valuations, err := valueItems(DB, userID, method, asOf)
+		if rateErr, ok := err.(*movementRateError); ok {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to convert purchase costs: " + rateErr.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to value inventory: " + err.Error()})
+			return
+		}
+
+		if reportingCurrency != "" {
+			if err := convertValuations(DB, valuations, reportingCurrency, asOf); err != nil {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to convert valuation: " + err.Error()})
+				return
+			}
+		}
+
+		totals := make(map[string]float64)
+		for _, valuation := range valuations {
+			totals[valuation.Currency] += valuation.Value
+		}
+
+		response := gin.H{
+			"method":   method,
+			"as_of":    asOf,
+			"group_by": groupBy,
+			"totals":   totals,
+		}
+		if reportingCurrency != "" {
+			response["currency"] = reportingCurrency
+			response["total"] = totals[reportingCurrency]
+		}
+
+		switch groupBy {
+		case "item":
+			response["items"] = valuations
+		case "location", "category":
+			groups, err := groupValuations(DB, userID, groupBy, valuations)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to group valuation: " + err.Error()})
+				return
+			}
+			response["groups"] = groups
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// valueItems replays each item's stock movements up to asOf and values what is left on hand
+func valueItems(DB *gorm.DB, userID uint, method string, asOf time.Time) ([]itemValuation, error) {
+	var items []models.Item
+	if err := DB.Where("user_id = ?", userID).Order("id").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	var movements []models.StockMovement
+	if err := DB.Where("user_id = ? AND occurred_at <= ?", userID, asOf).Order("occurred_at, id").Find(&movements).Error; err != nil {
+		return nil, err
+	}
+	movementsByItem := make(map[uint][]models.StockMovement)
+	for _, movement := range movements {
+		movementsByItem[movement.ItemID] = append(movementsByItem[movement.ItemID], movement)
+	}
+
+	// Items that predate the ledger have no movements at all
+	var ledgerItemIDs []uint
+	if err := DB.Model(&models.StockMovement{}).Where("user_id = ?", userID).Distinct().Pluck("item_id", &ledgerItemIDs).Error; err != nil {
+		return nil, err
+	}
+	inLedger := make(map[uint]bool)
+	for _, id := range ledgerItemIDs {
+		inLedger[id] = true
+	}
+
+	rates := make(map[string]float64)
+	valuations := make([]itemValuation, 0, len(items))
+	for _, item := range items {
+		itemMovements := movementsByItem[item.ID]
+		if err := convertMovementCosts(DB, itemMovements, itemCurrency(item), rates); err != nil {
+			return nil, err
+		}
+
+		var quantity int
+		var value float64
+		if len(itemMovements) == 0 {
+			// Value stock that predates the ledger at the item's purchase price
+			if inLedger[item.ID] || item.CreatedAt.After(asOf) {
+				continue
+			}
+			quantity, value = item.Quantity, float64(item.Quantity)*item.PurchasePrice
+		} else if method == costingAverage {
+			quantity, value = averageCost(itemMovements)
+		} else {
+			quantity, value = fifoCost(itemMovements)
+		}
+		if quantity == 0 {
+			continue
+		}
+
+		valuations = append(valuations, itemValuation{
+			ItemID:     item.ID,
+			Name:       item.Name,
+			LocationID: item.LocationID,
+			CategoryID: item.CategoryID,
+			Quantity:   quantity,
+			Value:      value,
+			Currency:   itemCurrency(item),
+		})
+	}
+	return valuations, nil
+}
+
+// movementRateError means a purchase cost couldn't be converted for lack of an exchange rate
+type movementRateError struct {
+	err error
+}
+
+func (e *movementRateError) Error() string {
+	return e.err.Error()
+}
+
+// convertMovementCosts converts unit costs paid in another currency into the item's currency
+// at the rate effective when the stock came in. rates caches lookups across items.
+func convertMovementCosts(DB *gorm.DB, movements []models.StockMovement, itemCurrency string, rates map[string]float64) error {
+	for i, movement := range movements {
+		if movement.Currency == "" || movement.Currency == itemCurrency || movement.UnitCost == 0 {
+			continue
+		}
+		key := movement.Currency + ":" + itemCurrency + ":" + movement.OccurredAt.Format("2006-01-02")
+		rate, ok := rates[key]
+		if !ok {
+			var err error
+			if rate, err = currency.Rate(DB, movement.Currency, itemCurrency, movement.OccurredAt); err != nil {
+				return &movementRateError{err}
+			}
+			rates[key] = rate
+		}
+		movements[i].UnitCost = movement.UnitCost * rate
+		movements[i].Currency = itemCurrency
+	}
+	return nil
+}
+
+// convertValuations converts item values into the reporting currency at the rates effective on the given date
+func convertValuations(DB *gorm.DB, valuations []itemValuation, reportingCurrency string, on time.Time) error {
+	rates := map[string]float64{reportingCurrency: 1}
+	for i := range valuations {
+		rate, ok := rates[valuations[i].Currency]
+		if !ok {
+			var err error
+			if rate, err = currency.Rate(DB, valuations[i].Currency, reportingCurrency, on); err != nil {
+				return err
+			}
+			rates[valuations[i].Currency] = rate
+		}
+		valuations[i].OriginalValue = valuations[i].Value
+		valuations[i].OriginalCurrency = valuations[i].Currency
+		valuations[i].Value *= rate
+		valuations[i].Currency = reportingCurrency
+	}
+	return nil
+}
+
+// fifoCost values stock on hand assuming the oldest units are used up first
+func fifoCost(movements []models.StockMovement) (int, float64) {
+	var layers []costLayer
+	for _, movement := range movements {
+		if movement.Quantity > 0 {
+			layers = append(layers, costLayer{quantity: movement.Quantity, unitCost: movement.UnitCost})
+			continue
+		}
+		remaining := -movement.Quantity
+		for remaining > 0 && len(layers) > 0 {
+			if layers[0].quantity > remaining {
+				layers[0].quantity -= remaining
+				remaining = 0
+			} else {
+				remaining -= layers[0].quantity
+				layers = layers[1:]
+			}
+		}
+	}
+
+	var quantity int
+	var value float64
+	for _, layer := range layers {
+		quantity += layer.quantity
+		value += float64(layer.quantity) * layer.unitCost
+	}
+	return quantity, value
+}
+
+// averageCost values stock on hand at the weighted average cost of everything received
+func averageCost(movements []models.StockMovement) (int, float64) {
+	var quantity int
+	var value float64
+	for _, movement := range movements {
+		if movement.Quantity > 0 {
+			quantity += movement.Quantity
+			value += float64(movement.Quantity) * movement.UnitCost
+			continue
+		}
+		if quantity == 0 {
+			continue
+		}
+		out := -movement.Quantity
+		if out > quantity {
+			out = quantity
+		}
+		value -= value * float64(out) / float64(quantity)
+		quantity -= out
+	}
+	return quantity, value
+}
+
+// groupValuations totals item valuations by location or category
+func groupValuations(DB *gorm.DB, userID uint, groupBy string, valuations []itemValuation) ([]valuationGroup, error) {
+	names := make(map[uint]string)
+	if groupBy == "location" {
+		var locations []models.Location
+		if err := DB.Where("user_id = ? OR user_id = 0 OR user_id IS NULL", userID).Find(&locations).Error; err != nil {
+			return nil, err
+		}
+		for _, location := range locations {
+			names[location.ID] = location.Name
+		}
+	} else {
+		var categories []models.Category
+		if err := DB.Where("user_id = ?", userID).Find(&categories).Error; err != nil {
+			return nil, err
+		}
+		for _, category := range categories {
+			names[category.ID] = category.Name
+		}
+	}
+
+	groups := []valuationGroup{}
+	index := make(map[string]int)
+	for _, valuation := range valuations {
+		var id *uint
+		if groupBy == "location" {
+			if valuation.LocationID != 0 {
+				locationID := valuation.LocationID
+				id = &locationID
+			}
+		} else {
+			id = valuation.CategoryID
+		}
+
+		key := "none"
+		name := "Unassigned"
+		if groupBy == "category" {
+			name = "Uncategorised"
+		}
+		if id != nil {
+			key = fmt.Sprint(*id)
+			name = names[*id]
+		}
+
+		position, ok := index[key]
+		if !ok {
+			position = len(groups)
+			index[key] = position
+			groups = append(groups, valuationGroup{ID: id, Name: name, Totals: make(map[string]float64)})
+		}
+		groups[position].Quantity += valuation.Quantity
+		groups[position].Totals[valuation.Currency] += valuation.Value
+	}
+	return groups, nil
+}
@@ -0,0 +1,167 @@
+// schedule/cron.go
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLookahead bounds the search for the next run of a rule that rarely or never matches (e.g. Feb 30)
+const maxLookahead = 5 * 366 * 24 * time.Hour
+
+// field describes one of the five cron fields
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// Rule is a parsed five-field cron expression: minute hour day-of-month month day-of-week.
+// Each field accepts *, numbers, ranges (1-5), lists (1,15) and steps (*/2, 1-10/3).
+// Sunday is 0 (7 is accepted too).
+type Rule struct {
+	minutes, hours, days, months, weekdays map[int]bool
+	anyDay, anyWeekday                     bool
+}
+
+// Parse reads a cron expression such as "0 9 1 */3 *" (09:00 on the first of every third month)
+func Parse(expression string) (Rule, error) {
+	parts := strings.Fields(expression)
+	if len(parts) != len(fields) {
+		return Rule{}, fmt.Errorf("expected 5 fields (minute hour day month weekday), got %d", len(parts))
+	}
+
+	sets := make([]map[int]bool, len(fields))
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return Rule{}, err
+		}
+		sets[i] = set
+	}
+	// 7 is an alias for Sunday
+	if sets[4][7] {
+		delete(sets[4], 7)
+		sets[4][0] = true
+	}
+
+	return Rule{
+		minutes:    sets[0],
+		hours:      sets[1],
+		days:       sets[2],
+		months:     sets[3],
+		weekdays:   sets[4],
+		anyDay:     parts[2] == "*",
+		anyWeekday: parts[4] == "*",
+	}, nil
+}
+
+// Next returns the first time strictly after the given time that matches the rule, in the
+// given time's location. The search walks the wall clock, so across daylight saving changes a
+// time that is skipped runs just after the change and a time that repeats runs only once.
+func (rule Rule) Next(after time.Time) (time.Time, error) {
+	loc := after.Location()
+	// Every wall-clock minute exists exactly once in UTC
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute(), 0, 0, time.UTC).Add(time.Minute)
+	limit := t.Add(maxLookahead)
+	for t.Before(limit) {
+		if !rule.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !rule.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !rule.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !rule.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+		// A time skipped by spring forward comes back before the change; move it past the gap
+		if wall := time.Date(next.Year(), next.Month(), next.Day(), next.Hour(), next.Minute(), 0, 0, time.UTC); !wall.Equal(t) {
+			next = next.Add(t.Sub(wall))
+		}
+		// The second pass through a repeated hour maps back to the first, which has already gone
+		if !next.After(after) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return next, nil
+	}
+	return time.Time{}, fmt.Errorf("rule never matches")
+}
+
+// matchesDay follows cron's convention: when both day fields are restricted, either may match
+func (rule Rule) matchesDay(t time.Time) bool {
+	day := rule.days[t.Day()]
+	weekday := rule.weekdays[int(t.Weekday())]
+	switch {
+	case rule.anyDay && rule.anyWeekday:
+		return true
+	case rule.anyDay:
+		return weekday
+	case rule.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// parseField expands one comma-separated cron field into the set of values it allows
+func parseField(value string, f field) (map[int]bool, error) {
+	max := f.max
+	if f.name == "day of week" {
+		max = 7
+	}
+
+	set := make(map[int]bool)
+	for _, part := range strings.Split(value, ",") {
+		step := 1
+		if base, stepValue, found := strings.Cut(part, "/"); found {
+			n, err := strconv.Atoi(stepValue)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step %q in %s", stepValue, f.name)
+			}
+			part, step = base, n
+		}
+
+		start, end := f.min, max
+		if part != "*" {
+			low, high, isRange := strings.Cut(part, "-")
+			var err error
+			if start, err = strconv.Atoi(low); err != nil {
+				return nil, fmt.Errorf("invalid %s %q", f.name, part)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(high); err != nil {
+					return nil, fmt.Errorf("invalid %s %q", f.name, part)
+				}
+			} else if step > 1 {
+				end = max // "5/10" means from 5 onwards
+			}
+		}
+		if start < f.min || end > max || start > end {
+			return nil, fmt.Errorf("%s must be between %d and %d", f.name, f.min, max)
+		}
+
+		for n := start; n <= end; n += step {
+			set[n] = true
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,117 @@
+// schedule/cron_test.go
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantErr    bool
+	}{
+		{"every minute", "* * * * *", false},
+		{"step", "*/15 * * * *", false},
+		{"range", "0 9 * * 1-5", false},
+		{"list", "0 9,17 1,15 * *", false},
+		{"range with step", "0 0 1-31/10 * *", false},
+		{"start with step", "5/10 * * * *", false},
+		{"sunday as 7", "0 0 * * 7", false},
+		{"too few fields", "* * * *", true},
+		{"too many fields", "* * * * * *", true},
+		{"minute out of range", "60 * * * *", true},
+		{"hour out of range", "0 24 * * *", true},
+		{"day zero", "0 0 0 * *", true},
+		{"month out of range", "0 0 1 13 *", true},
+		{"weekday out of range", "0 0 * * 8", true},
+		{"backwards range", "0 0 * * 5-1", true},
+		{"zero step", "*/0 * * * *", true},
+		{"bad step", "*/x * * * *", true},
+		{"not a number", "a * * * *", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.expression)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Parse(%q) error = %v, wantErr %v", test.expression, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	at := func(loc *time.Location, year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, loc)
+	}
+	// 2024-11-03 01:30 happens twice in New York; these are the first (EDT) and second (EST) passes
+	firstPass := at(time.UTC, 2024, time.November, 3, 5, 30).In(newYork)
+	secondPass := at(time.UTC, 2024, time.November, 3, 6, 30).In(newYork)
+
+	tests := []struct {
+		name       string
+		expression string
+		after      time.Time
+		want       time.Time
+	}{
+		{"next minute", "* * * * *", at(time.UTC, 2024, time.May, 1, 10, 0), at(time.UTC, 2024, time.May, 1, 10, 1)},
+		{"strictly after", "0 10 * * *", at(time.UTC, 2024, time.May, 1, 10, 0), at(time.UTC, 2024, time.May, 2, 10, 0)},
+		{"seconds are dropped", "* * * * *", time.Date(2024, time.May, 1, 10, 0, 30, 0, time.UTC), at(time.UTC, 2024, time.May, 1, 10, 1)},
+		{"step", "*/15 * * * *", at(time.UTC, 2024, time.May, 1, 10, 16), at(time.UTC, 2024, time.May, 1, 10, 30)},
+		{"step wraps the hour", "*/15 * * * *", at(time.UTC, 2024, time.May, 1, 10, 50), at(time.UTC, 2024, time.May, 1, 11, 0)},
+		{"start with step", "5/20 * * * *", at(time.UTC, 2024, time.May, 1, 10, 26), at(time.UTC, 2024, time.May, 1, 10, 45)},
+		{"weekday range skips the weekend", "0 9 * * 1-5", at(time.UTC, 2024, time.May, 3, 10, 0), at(time.UTC, 2024, time.May, 6, 9, 0)},
+		{"sunday as 7", "0 0 * * 7", at(time.UTC, 2024, time.May, 1, 0, 0), at(time.UTC, 2024, time.May, 5, 0, 0)},
+		{"sunday as 0", "0 0 * * 0", at(time.UTC, 2024, time.May, 1, 0, 0), at(time.UTC, 2024, time.May, 5, 0, 0)},
+		{"day of month or weekday, weekday first", "0 0 13 * 5", at(time.UTC, 2024, time.May, 1, 0, 0), at(time.UTC, 2024, time.May, 3, 0, 0)},
+		{"day of month or weekday, day first", "0 0 13 * 5", at(time.UTC, 2024, time.May, 10, 12, 0), at(time.UTC, 2024, time.May, 13, 0, 0)},
+		{"month rollover", "0 0 1 * *", at(time.UTC, 2024, time.May, 15, 0, 0), at(time.UTC, 2024, time.June, 1, 0, 0)},
+		{"year rollover", "0 0 1 1 *", at(time.UTC, 2024, time.May, 15, 0, 0), at(time.UTC, 2025, time.January, 1, 0, 0)},
+		{"31st skips short months", "0 0 31 * *", at(time.UTC, 2024, time.May, 31, 12, 0), at(time.UTC, 2024, time.July, 31, 0, 0)},
+		{"leap day", "0 0 29 2 *", at(time.UTC, 2024, time.March, 1, 0, 0), at(time.UTC, 2028, time.February, 29, 0, 0)},
+		{"quarterly", "0 9 1 */3 *", at(time.UTC, 2024, time.February, 1, 9, 0), at(time.UTC, 2024, time.April, 1, 9, 0)},
+		{"result in the given location", "0 9 * * *", at(newYork, 2024, time.May, 1, 10, 0), at(newYork, 2024, time.May, 2, 9, 0)},
+		{"skipped time runs after spring forward", "30 2 * * *", at(newYork, 2024, time.March, 9, 3, 0), at(time.UTC, 2024, time.March, 10, 7, 30)},
+		{"day after spring forward", "30 2 * * *", at(time.UTC, 2024, time.March, 10, 7, 30).In(newYork), at(newYork, 2024, time.March, 11, 2, 30)},
+		{"hourly across spring forward", "0 * * * *", at(newYork, 2024, time.March, 10, 1, 30), at(newYork, 2024, time.March, 10, 3, 0)},
+		{"repeated time runs on the first pass", "30 1 * * *", at(newYork, 2024, time.November, 2, 12, 0), firstPass},
+		{"repeated time runs once", "30 1 * * *", firstPass, at(newYork, 2024, time.November, 4, 1, 30)},
+		{"after the second pass", "30 1 * * *", secondPass, at(newYork, 2024, time.November, 4, 1, 30)},
+		{"hourly across fall back", "0 * * * *", secondPass, at(newYork, 2024, time.November, 3, 2, 0)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule, err := Parse(test.expression)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", test.expression, err)
+			}
+			got, err := rule.Next(test.after)
+			if err != nil {
+				t.Fatalf("Next(%v) returned error: %v", test.after, err)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("Next(%v) = %v, want %v", test.after, got, test.want)
+			}
+			if got.Location() != test.after.Location() {
+				t.Errorf("Next(%v) returned location %v, want %v", test.after, got.Location(), test.after.Location())
+			}
+		})
+	}
+}
+
+func TestNextNeverMatches(t *testing.T) {
+	for _, expression := range []string{"0 0 30 2 *", "0 0 31 4,6,9,11 *"} {
+		rule, err := Parse(expression)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", expression, err)
+		}
+		if got, err := rule.Next(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+			t.Errorf("Next for %q = %v, want an error", expression, got)
+		}
+	}
+}